@@ -8,8 +8,9 @@ import (
 
 func New() *v2.App {
 	app := &v2.App{
-		Name:  "dockerenv",
-		Usage: "extract information from docker environment variables",
+		Name:                 "dockerenv",
+		Usage:                "extract information from docker environment variables",
+		EnableBashCompletion: true,
 		Flags: []v2.Flag{
 			&v2.StringFlag{
 				Name:    "container-id",
@@ -23,12 +24,27 @@ func New() *v2.App {
 				Value:   "",
 				Usage:   "The variable name to extract values from",
 			},
+			&v2.StringSliceFlag{
+				Name:    "host",
+				Aliases: []string{"H"},
+				Usage:   "Docker daemon to query (repeatable, default: DOCKER_HOST)",
+			},
 		},
 		Commands: []*v2.Command{
 			commands.ExportCommand(),
+			commands.Import(),
 			commands.ListValues(),
 			commands.GetValue(),
 			commands.TLS(),
+			commands.Search(),
+			commands.UI(),
+			commands.Compose(),
+			commands.RunCmd(),
+			commands.ShellEnv(),
+			commands.Report(),
+			commands.Schema(),
+			commands.Completion(),
+			commands.CompleteCommand(),
 		},
 	}
 
@@ -0,0 +1,244 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/completion"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+const (
+	completionTimeout = 500 * time.Millisecond
+	completionTTL     = 10 * time.Second
+	// completionRefresh is how long the background refresh started for
+	// lookups that timed out waits for the daemon.
+	completionRefresh = 30 * time.Second
+	// completionRefreshEnv marks the __complete process that refreshes
+	// the cache in the background.
+	completionRefreshEnv = "DOCKERENV_COMPLETE_REFRESH"
+)
+
+var (
+	containerFlagNames = []string{"--container-id", "--id", "-c"}
+	varFlagNames       = []string{"--var-name", "--var", "-v", "--cert", "--key", "--ca-cert"}
+	hostFlagNames      = []string{"--host", "-H"}
+
+	// completionInspector and startRefresh are replaced in tests.
+	completionInspector = inspector.NewForHost
+	startRefresh        = detach
+)
+
+func Completion() *v2.Command {
+	return &v2.Command{
+		Name:      "completion",
+		Usage:     "prints a shell completion script (bash, zsh, fish)",
+		ArgsUsage: "SHELL",
+		Action: func(c *v2.Context) error {
+			if c.NArg() != 1 {
+				fmt.Println("Must specify a shell: bash, zsh or fish")
+				return v2.ShowSubcommandHelp(c)
+			}
+			script, err := completion.Script(c.Args().First(), c.App.Name)
+			if err != nil {
+				return err
+			}
+			fmt.Print(script)
+			return nil
+		},
+	}
+}
+
+// CompleteCommand is called by the completion scripts with the words typed
+// so far. It prints one candidate per line.
+func CompleteCommand() *v2.Command {
+	return &v2.Command{
+		Name:            "__complete",
+		Hidden:          true,
+		SkipFlagParsing: true,
+		Action: func(c *v2.Context) error {
+			words := c.Args().Slice()
+			if len(words) == 0 {
+				words = []string{""}
+			}
+			cp := &completer{cache: completion.NewCache(completionTTL), timeout: completionTimeout}
+			refreshing := os.Getenv(completionRefreshEnv) != ""
+			if refreshing {
+				cp.timeout = completionRefresh
+			}
+			for _, candidate := range cp.complete(c.App, words) {
+				fmt.Println(candidate)
+			}
+			// The shell waits for us to exit, so a slow daemon is left to
+			// a process of its own that fills the cache for the next tab.
+			if cp.timedOut && !refreshing {
+				args := append([]string{c.Command.Name}, c.Args().Slice()...)
+				if err := startRefresh(args, append(os.Environ(), completionRefreshEnv+"=1")); err != nil {
+					log.Debugf("failed to refresh completions in the background: %s", err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// completer looks up candidates through the cache, noting whether any
+// lookup timed out.
+type completer struct {
+	cache    *completion.Cache
+	timeout  time.Duration
+	timedOut bool
+}
+
+func (cp *completer) complete(app *v2.App, words []string) []string {
+	cur := words[len(words)-1]
+	prev := ""
+	if len(words) > 1 {
+		prev = words[len(words)-2]
+	}
+
+	// --flag=value is completed as a whole word in zsh and fish
+	prefix := ""
+	if i := strings.Index(cur, "="); i > 0 && strings.HasPrefix(cur, "-") {
+		prefix, prev, cur = cur[:i+1], cur[:i], cur[i+1:]
+	}
+
+	var candidates []string
+	switch {
+	case contains(containerFlagNames, prev):
+		candidates = cp.containers(flagValue(words, hostFlagNames))
+	case contains(varFlagNames, prev):
+		candidates = cp.vars(flagValue(words, hostFlagNames), flagValue(words, containerFlagNames))
+	case strings.HasPrefix(cur, "-"):
+		candidates = flagNames(app.Flags)
+		if cmd := lastCommand(app.Commands, words); cmd != nil {
+			candidates = append(candidates, flagNames(cmd.Flags)...)
+		}
+	default:
+		commands := app.Commands
+		if cmd := lastCommand(app.Commands, words); cmd != nil {
+			commands = cmd.Subcommands
+		}
+		for _, cmd := range commands {
+			if !cmd.Hidden {
+				candidates = append(candidates, cmd.Name)
+			}
+		}
+	}
+
+	var out []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, cur) {
+			out = append(out, prefix+candidate)
+		}
+	}
+	return out
+}
+
+func (cp *completer) containers(host string) []string {
+	values, err := cp.cache.Get("containers|"+host, cp.timeout, func() ([]string, error) {
+		ins, err := completionInspector(host)
+		if err != nil {
+			return nil, err
+		}
+		list, err := ins.ListContainers()
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, ctr := range list {
+			values = append(values, ctr.Name, ctr.ShortID())
+		}
+		return values, nil
+	})
+	if err != nil {
+		cp.timedOut = cp.timedOut || errors.Is(err, completion.ErrTimeout)
+		log.Debugf("container completion: %s", err)
+	}
+	return values
+}
+
+func (cp *completer) vars(host, containerId string) []string {
+	if containerId == "" {
+		return nil
+	}
+	values, err := cp.cache.Get("vars|"+host+"|"+containerId, cp.timeout, func() ([]string, error) {
+		ins, err := completionInspector(host)
+		if err != nil {
+			return nil, err
+		}
+		ctr, err := ins.Inspect(containerId)
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, ev := range ctr.Env {
+			values = append(values, ev.Name)
+		}
+		return values, nil
+	})
+	if err != nil {
+		cp.timedOut = cp.timedOut || errors.Is(err, completion.ErrTimeout)
+		log.Debugf("variable completion: %s", err)
+	}
+	return values
+}
+
+// flagValue returns the value given to any of names in words, in either
+// "--name value" or "--name=value" form.
+func flagValue(words []string, names []string) string {
+	for i, w := range words[:len(words)-1] {
+		if contains(names, w) && i+1 < len(words)-1 {
+			return words[i+1]
+		}
+		for _, name := range names {
+			if strings.HasPrefix(w, name+"=") {
+				return strings.TrimPrefix(w, name+"=")
+			}
+		}
+	}
+	return ""
+}
+
+// lastCommand returns the most deeply nested command named in words.
+func lastCommand(commands []*v2.Command, words []string) *v2.Command {
+	var found *v2.Command
+	for _, w := range words[:len(words)-1] {
+		for _, cmd := range commands {
+			if cmd.HasName(w) {
+				found = cmd
+				commands = cmd.Subcommands
+				break
+			}
+		}
+	}
+	return found
+}
+
+func flagNames(flags []v2.Flag) []string {
+	var names []string
+	for _, f := range flags {
+		for _, name := range f.Names() {
+			if len(name) == 1 {
+				names = append(names, "-"+name)
+			} else {
+				names = append(names, "--"+name)
+			}
+		}
+	}
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+// slowInspector blocks listing containers until release is closed.
+type slowInspector struct {
+	inspector.Inspector
+	release chan struct{}
+}
+
+func (s *slowInspector) ListContainers(filters ...string) ([]inspector.Container, error) {
+	<-s.release
+	return []inspector.Container{{ID: "aaa111aaa111", Name: "api"}}, nil
+}
+
+func TestCompleteReturnsBeforeSlowLookup(t *testing.T) {
+	cacheDir := t.TempDir()
+	old, had := os.LookupEnv("XDG_CACHE_HOME")
+	os.Setenv("XDG_CACHE_HOME", cacheDir)
+	os.Unsetenv(completionRefreshEnv)
+	slow := &slowInspector{release: make(chan struct{})}
+	var refreshArgs, refreshEnv []string
+	completionInspector = func(host string) (inspector.Inspector, error) { return slow, nil }
+	startRefresh = func(args, env []string) error {
+		refreshArgs, refreshEnv = args, env
+		return nil
+	}
+	defer func() {
+		close(slow.release)
+		completionInspector, startRefresh = inspector.NewForHost, detach
+		if had {
+			os.Setenv("XDG_CACHE_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+
+	app := &v2.App{Name: "dockerenv", Commands: []*v2.Command{CompleteCommand()}}
+	start := time.Now()
+	if err := app.Run([]string{"dockerenv", "__complete", "get", "-c", ""}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > completionTimeout+time.Second {
+		t.Errorf("__complete took %s with a lookup that never finished", elapsed)
+	}
+	if want := []string{"__complete", "get", "-c", ""}; !reflect.DeepEqual(refreshArgs, want) {
+		t.Errorf("background refresh args = %q, want %q", refreshArgs, want)
+	}
+	found := false
+	for _, kv := range refreshEnv {
+		found = found || strings.HasPrefix(kv, completionRefreshEnv+"=")
+	}
+	if !found {
+		t.Errorf("background refresh env lacks %s", completionRefreshEnv)
+	}
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/docker/docker/api/types"
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/compose"
+	"github.com/cmattoon/dockerenv/pkg/dotenv"
+	"github.com/cmattoon/dockerenv/pkg/layout"
+	"github.com/cmattoon/dockerenv/pkg/spec"
+)
+
+func Compose() *v2.Command {
+	return &v2.Command{
+		Name:  "compose",
+		Usage: "docker-compose helpers",
+		Subcommands: []*v2.Command{
+			{
+				Name:  "generate",
+				Usage: "prints a compose service that recreates a running container",
+				Flags: []v2.Flag{
+					&v2.StringFlag{
+						Name:  "service",
+						Usage: "Service name (default: the compose service label, or the container name)",
+					},
+					&v2.StringFlag{
+						Name:  "env-file",
+						Usage: "Write the environment to this file (compose dialect) and reference it with env_file",
+					},
+				},
+				Action: func(c *v2.Context) error {
+					containerId := c.String("container-id")
+					if containerId == "" {
+						fmt.Println("Must specify --container-id")
+						return v2.ShowSubcommandHelp(c)
+					}
+
+					s, raw, err := inspectSpec(c, containerId)
+					if err != nil {
+						return err
+					}
+
+					opts := compose.Options{Service: c.String("service"), EnvFile: c.String("env-file")}
+					if opts.Service == "" && raw.Config != nil {
+						opts.Service = raw.Config.Labels[layout.ServiceLabel]
+					}
+					if opts.EnvFile != "" {
+						data, err := dotenv.Marshal(s.Env, dotenv.Compose)
+						if err != nil {
+							return err
+						}
+						if err := ioutil.WriteFile(opts.EnvFile, data, 0600); err != nil {
+							return err
+						}
+					}
+
+					f, err := compose.Generate(s, opts)
+					if err != nil {
+						return err
+					}
+					out, err := f.Marshal()
+					if err != nil {
+						return err
+					}
+					_, err = os.Stdout.Write(out)
+					return err
+				},
+			},
+		},
+	}
+}
+
+// inspectSpec returns the creation-time spec of a container, compared
+// against its image when the image is still present.
+func inspectSpec(c *v2.Context, containerId string) (spec.Spec, types.ContainerJSON, error) {
+	ins, err := newInspector(c)
+	if err != nil {
+		return spec.Spec{}, types.ContainerJSON{}, err
+	}
+	raw, err := ins.InspectRaw(containerId)
+	if err != nil {
+		return spec.Spec{}, raw, err
+	}
+	img, err := ins.InspectImage(raw.Image)
+	if err != nil {
+		log.Warningf("comparing against an empty image: %s", err)
+		return spec.FromContainer(raw, nil), raw, nil
+	}
+	return spec.FromContainer(raw, &img), raw, nil
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"strings"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/decode"
+)
+
+func decodeFlags() []v2.Flag {
+	return []v2.Flag{
+		&v2.StringFlag{
+			Name:  "decode",
+			Usage: "Decode values with a comma separated chain of: " + strings.Join(decode.StepNames(), ", ") + ", json:PATH",
+		},
+		&v2.BoolFlag{
+			Name:  "auto-decode",
+			Usage: "Detect and undo layers of encoding, reporting what was undone",
+		},
+	}
+}
+
+// valueDecoder returns a function applying --decode or --auto-decode to a
+// named value, or nil if neither was given. Extra steps run before --decode.
+func valueDecoder(c *v2.Context, extra ...string) (func(name, value string) (string, error), error) {
+	spec := strings.Join(append(extra, c.String("decode")), ",")
+	chain, err := decode.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	auto := c.Bool("auto-decode")
+	if len(chain) == 0 && !auto {
+		return nil, nil
+	}
+
+	return func(name, value string) (string, error) {
+		v, err := chain.Apply(value)
+		if err != nil {
+			return value, err
+		}
+		if auto {
+			var applied decode.Chain
+			if v, applied = decode.Auto(v); len(applied) > 0 {
+				log.Infof("%s: auto-decoded %s", name, applied)
+			}
+		}
+		return v, nil
+	}, nil
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detach starts this executable again with args and env, with its stdio
+// on /dev/null and in a session of its own, so that it outlives the shell
+// that started us, and doesn't wait for it.
+func detach(args []string, env []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer null.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = null, null, null
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
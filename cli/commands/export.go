@@ -1,43 +1,28 @@
 package commands
 
 import (
-	"bytes"
-	"context"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/sirupsen/logrus"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
-
+	"github.com/sirupsen/logrus"
 	v2 "github.com/urfave/cli/v2"
-
 	"gopkg.in/yaml.v2"
 
+	"github.com/cmattoon/dockerenv/pkg/envdoc"
+	"github.com/cmattoon/dockerenv/pkg/export"
 	"github.com/cmattoon/dockerenv/pkg/inspector"
-	//"golang.org/x/crypto/ssh/terminal"
+	"github.com/cmattoon/dockerenv/pkg/layout"
+	"github.com/cmattoon/dockerenv/pkg/spec"
+	"github.com/cmattoon/dockerenv/pkg/templates"
 )
 
 var log *logrus.Logger
-var S3_BUCKET string
-var OUTPUT_DIR string
-var S3_REGION string
 
 func init() {
 	log = logrus.New()
-	S3_BUCKET, _ = os.LookupEnv("S3_BUCKET")
-	OUTPUT_DIR, _ = os.LookupEnv("OUTPUT_DIR")
-	if r, ok := os.LookupEnv("S3_REGION"); ok && r != "" {
-		S3_REGION = r
-	} else if r, ok := os.LookupEnv("AWS_DEFAULT_REGION"); ok && r != "" {
-		S3_REGION = r
-	}
+	export.Log = log
 }
 
 func ExportCommand() *v2.Command {
@@ -48,32 +33,88 @@ func ExportCommand() *v2.Command {
 		Flags: []v2.Flag{
 			&v2.StringFlag{
 				Name:  "format",
-				Usage: "The output format (yaml, env, json, ssm, s3)",
+				Usage: "The output format (" + strings.Join(export.FormatNames(), ", ") + ") or a Go template",
+			},
+			&v2.StringSliceFlag{
+				Name:  "to",
+				Usage: "Where to write (repeatable): -, file:///dir, s3://bucket/prefix, ssm:///prefix, secretsmanager://name, vault://mount/path or tar:///file.tgz, with options as ?key=value (default: -)",
+			},
+			&v2.StringFlag{
+				Name:  "env-dialect",
+				Value: "compose",
+				Usage: "Quoting rules for env files: compose (quoted, escaped) or docker (--env-file, literal)",
 			},
 			&v2.StringFlag{
 				Name:  "path-prefix",
-				Usage: "The SSM or S3 path prefix. Should start with /",
+				Usage: "The SSM prefix --format ecs refers secrets to, and --format ssm writes to. Should start with /",
 			},
 			&v2.StringFlag{
 				Name:  "container-id",
 				Usage: "The container ID (default: ALL)",
 			},
+			filterFlag(),
 			&v2.StringFlag{
-				Name:  "s3-bucket",
-				Usage: "The S3 Bucket name (no protocol)",
-			},
-			&v2.StringFlag{
-				Name:  "output-dir",
-				Usage: "output directory",
+				Name:  "layout",
+				Value: "id",
+				Usage: "Where each container is written: id, compose, or a Go template such as '{{.Project}}/{{.Name}}'",
 			},
 			&v2.BoolFlag{
 				Name:  "snapshot",
-				Usage: "Also writes a set of params useful for restarting the container",
+				Usage: "Also writes a set of params useful for restarting the container (per-container formats, json and kv)",
 			},
 			&v2.BoolFlag{
 				Name:  "overwrite",
-				Usage: "Set this to overwrite an existing set of files",
+				Usage: "Replace SSM parameters that already exist with a different value",
+			},
+			secretPatternFlag(),
+			&v2.StringFlag{
+				Name:  "k8s-name",
+				Value: "compose",
+				Usage: "Base name of k8s objects, as a layout (id, compose or a template)",
+			},
+			&v2.StringFlag{
+				Name:  "namespace",
+				Usage: "Namespace of k8s objects (default: the compose project)",
+			},
+			&v2.StringSliceFlag{
+				Name:  "k8s-label",
+				Usage: "Extra key=value label for k8s objects (repeatable)",
+			},
+			&v2.StringFlag{
+				Name:  "systemd-unit",
+				Value: "none",
+				Usage: "Unit written next to the EnvironmentFile: none, service (runs the program natively) or podman",
+			},
+			&v2.BoolFlag{
+				Name:  "systemd-inline",
+				Usage: "Put the environment in the unit as Environment= lines instead of referencing an EnvironmentFile",
+			},
+			&v2.StringFlag{
+				Name:  "systemd-env-dir",
+				Value: "/etc/dockerenv",
+				Usage: "Directory the unit expects its EnvironmentFile in",
+			},
+			&v2.StringFlag{
+				Name:  "key-convention",
+				Usage: "Key naming for tfvars and helm: keep, snake, camel or upper (default: snake for tfvars, camel for helm)",
+			},
+			&v2.StringFlag{
+				Name:  "sensitive",
+				Value: "split",
+				Usage: "Where tfvars and helm put secret values: split (a separate file) or inline",
+			},
+			&v2.StringFlag{
+				Name:  "helm-root",
+				Value: "env",
+				Usage: "Key the helm values are nested under (empty for the top level)",
+			},
+			&v2.BoolFlag{
+				Name:  "rfc4180",
+				Usage: "Write csv and tsv fields as-is, without the ' that stops spreadsheets running values starting with = + - @ as formulas",
 			},
+			valuePolicyFlag(),
+			queryFlag(),
+			templateFlag(),
 		},
 	}
 }
@@ -100,20 +141,28 @@ func newContainerInfo(container types.ContainerJSON) ContainerInfo {
 	return containerInfo
 }
 
+func newSnapshot(container types.ContainerJSON) *envdoc.Snapshot {
+	snap := &envdoc.Snapshot{
+		ImageID: container.Image,
+		Created: container.Created,
+	}
+	if container.Config != nil {
+		snap.Entrypoint = container.Config.Entrypoint
+		snap.Cmd = container.Config.Cmd
+		snap.WorkingDir = container.Config.WorkingDir
+		snap.User = container.Config.User
+	}
+	if container.HostConfig != nil {
+		snap.RestartPolicy = container.HostConfig.RestartPolicy.Name
+	}
+	return snap
+}
+
 func exportContainerEnvAction(c *v2.Context) error {
 	pathPrefix := c.String("path-prefix")
 	if len(pathPrefix) < 1 || !strings.HasPrefix(pathPrefix, "/") {
 		pathPrefix = "/"
 	}
-	outputDir := c.String("output-dir")
-	if outputDir != "" {
-		OUTPUT_DIR = outputDir
-	}
-	log.Infof("Using output directory at %s", OUTPUT_DIR)
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		panic(err)
-	}
 	var containerId string
 	if cid := c.String("container-id"); cid != "" {
 		containerId = cid
@@ -121,163 +170,198 @@ func exportContainerEnvAction(c *v2.Context) error {
 		containerId = "ALL"
 	}
 
-	// Get list of containers
-	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{})
-	if err != nil {
-		panic(err)
+	format := c.String("format")
+	if format == "" {
+		format = "env"
+	}
+	text := c.String("template")
+	if templates.IsTemplate(format) {
+		text = format
 	}
 
-	allValues := map[string]map[string]string{}
+	// --format ssm predates --to.
+	targets := c.StringSlice("to")
+	switch format {
+	case "s3":
+		return fmt.Errorf("--format s3 has been replaced by --to s3://bucket/prefix")
+	case "ssm":
+		format = "kv"
+		if len(targets) == 0 {
+			targets = []string{"ssm://" + pathPrefix}
+		}
+	}
+	if len(targets) == 0 {
+		targets = []string{"-"}
+	}
+
+	var fmtr export.Formatter
+	var f *export.Format
+	if text == "" {
+		var err error
+		if f, err = export.LookupFormat(format); err != nil {
+			return err
+		}
+		if fmtr, err = f.New(c); err != nil {
+			return err
+		}
+	}
+	if c.Bool("snapshot") && (f == nil || !f.PerContainer && !f.Snapshots) {
+		return fmt.Errorf("--snapshot is only written by per-container formats, json and kv")
+	}
 
-	ins, err := inspector.New()
+	inspectors, err := newInspectors(c)
 	if err != nil {
-		return fmt.Errorf("failed to create docker inspector: %w", err)
+		return err
 	}
+	cls, err := newClassifier(c)
+	if err != nil {
+		return err
+	}
+	lay, err := layout.Parse(c.String("layout"))
+	if err != nil {
+		return err
+	}
+
+	batch := &export.Batch{Index: layout.Index{}, Classifier: cls}
+	allValues := map[string]map[string]string{}
+	exported := []inspector.Container{}
+	used := map[string]bool{}
+	var extra []export.Item
+	// skipped counts containers that could not be inspected.
+	skipped := 0
 
 	snapshot := c.Bool("snapshot")
-	metaFiles := map[string][]byte{}
-	for _, container := range containers {
-		if containerId == "ALL" || containerId == container.ID {
-			if snapshot {
-				c, err := cli.ContainerInspect(context.Background(), container.ID)
-				info := newContainerInfo(c)
-				log.Debug(info)
-				meta, err := yaml.Marshal(info)
-				if err != nil {
-					return fmt.Errorf("Failed to marshal YAML: %w", err)
-				}
+	needSpec := f != nil && f.NeedsSpec != nil && f.NeedsSpec(c)
+	for _, ins := range inspectors {
+		// Get list of containers
+		containers, err := ins.ListContainers(c.StringSlice("filter")...)
+		if err != nil {
+			return err
+		}
 
-				metaFile := pathPrefix + "/containers/" + container.ID + "/container-meta.yaml"
-				// if err = writeFileData(metaFile, meta); err != nil {
-				// 	return fmt.Errorf("failed to write meta file data to %s: %w", metaFile, err)
-				// }
-				metaFiles[metaFile] = meta
+		for _, container := range containers {
+			if containerId != "ALL" && !matchesContainer(container, containerId) {
+				continue
 			}
-
-			values, err := ins.GetAllValues(container.ID)
+			ctr, err := ins.Inspect(container.ID)
 			if err != nil {
 				log.Error(err)
+				skipped++
 				continue
 			}
-			shortID := container.ID[0:8]
-			allValues[shortID] = values
-		}
-	}
 
-	format := c.String("format")
-	if format == "" {
-		format = "env"
-	}
-
-	switch format {
-	case "json":
-		return fmt.Errorf("finish me")
-
-	case "yaml":
-		data, err := yaml.Marshal(allValues)
-		if err != nil {
-			return fmt.Errorf("failed to marshal YAML: %w", err)
-		}
-		return ioutil.WriteFile("output.yaml", data, 0644)
-	case "ssm":
-		for cid, cenv := range allValues {
-			for k, v := range cenv {
-				ssmPath := fmt.Sprintf("%s/%s/%s", pathPrefix, cid, k)
-				log.Infof("Saving \033[33m%s\033[0m as \033[36m%s\033[0m", ssmPath, v)
+			shortID := ctr.ShortID()
+			ctrPath, err := lay.Path(ctr)
+			if err != nil {
+				return err
 			}
-		}
-	case "env", "s3":
-		var txt strings.Builder
-		for cid, cenv := range allValues {
-			for key, val := range cenv {
-				txt.WriteString(fmt.Sprintf("%s=\"%s\"\n", key, val))
+			if used[ctrPath] {
+				log.Warningf("%s is already used by another container; exporting %s to %s-%s", ctrPath, ctr.Name, ctrPath, shortID)
+				ctrPath = ctrPath + "-" + shortID
 			}
-			containersPrefix := pathPrefix + "/containers"
-			containerPrefix := containersPrefix + "/" + cid
-			envFileName := containerPrefix + "/container.env"
+			ec := &export.Container{Container: ctr, Path: ctrPath}
 
-			if format == "env" {
-				if err := writeFileData(envFileName, []byte(txt.String())); err != nil {
-					log.Errorf("failed to write to %s: %s", OUTPUT_DIR+envFileName, err)
-				}
-			} else if format == "s3" {
-				containersPrefix = pathPrefix + "/containers"
-				containerPrefix = containersPrefix + "/" + cid[0:8]
-				envFileName = containerPrefix + "/container.yml"
-
-				data, err := yaml.Marshal(allValues)
+			if snapshot || needSpec {
+				raw, err := ins.InspectRaw(container.ID)
 				if err != nil {
-					return fmt.Errorf("failed to marshal YAML: %w", err)
+					log.Errorf("skipping %s: %s", ctr.Name, err)
+					skipped++
+					continue
 				}
-
-				log.Infof("Writing YAML to S3 @ %s", envFileName)
-				if err := writeS3Data(envFileName, data); err != nil {
-					log.Errorf("failed to write to S3: %s", err)
+				ec.Raw = &raw
+				if snapshot {
+					info := newContainerInfo(raw)
+					log.Debug(info)
+					meta, err := yaml.Marshal(info)
+					if err != nil {
+						return fmt.Errorf("Failed to marshal YAML: %w", err)
+					}
+					extra = append(extra, export.Item{
+						Key:         path.Join(export.ContainersDir, ctrPath, "container-meta.yaml"),
+						Data:        meta,
+						ContentType: "application/yaml",
+						Container:   ec,
+					})
+					ec.Snapshot = newSnapshot(raw)
 				}
-			}
-		}
-		for name, data := range metaFiles {
-			if format == "s3" {
-				if err := writeS3Data(name, data); err != nil {
-					log.Errorf("failed to write to S3: %s", err)
+				if needSpec {
+					var imgPtr *types.ImageInspect
+					if img, err := ins.InspectImage(raw.Image); err == nil {
+						imgPtr = &img
+					}
+					s := spec.FromContainer(raw, imgPtr)
+					ec.Spec = &s
 				}
 			}
+
+			used[ctrPath] = true
+			batch.Index.Add(ctr, ctrPath)
+			batch.Containers = append(batch.Containers, ec)
+
+			values := map[string]string{}
+			for _, ev := range ctr.Env {
+				values[ev.Name] = ev.Value
+			}
+			allValues[shortID] = values
+			exported = append(exported, ctr)
 		}
 	}
-	return fmt.Errorf("not implemented")
-}
 
-func writeFileData(filename string, data []byte) error {
-	final_filename := OUTPUT_DIR + filename // prefix + filename
-	dirPath := filepath.Dir(final_filename)
-	log.Infof("Preparing to write %d bytes to %s", len(data), final_filename)
-	log.Infof("Creating directory %s", dirPath)
-	if err := os.MkdirAll(dirPath, os.ModePerm); err != nil {
-		log.Errorf("error creating directory: %s", err)
-		return nil
+	if ok, err := printQuery(c, allValues); ok {
+		return err
+	}
+	if ok, err := printTemplate(text, exported, ""); ok {
+		return err
 	}
 
-	log.Infof("Writing %d bytes to %s", len(data), final_filename)
-	return ioutil.WriteFile(final_filename, data, 0644)
-}
-
-func writeS3Data(filename string, data []byte) error {
-	var dryRun bool
-	if S3_BUCKET == "" {
-		dryRun = true
-		S3_BUCKET = "S3_BUCKET"
-		log.Warningf("Treating this as a dry run because S3_BUCKET Is not set")
+	items, err := fmtr.Format(batch)
+	if err != nil {
+		return err
+	}
+	if f.PerContainer {
+		idx, err := export.IndexItem(batch)
+		if err != nil {
+			return err
+		}
+		items = append(append(items, extra...), idx)
 	}
 
-	s3FullPath := fmt.Sprintf("s3://%s%s", S3_BUCKET, filename)
-	log.Infof("Writing %d bytes to %s", len(data), s3FullPath)
-	log.Debugf("\033[33m%s\033[0m", data)
-	if dryRun {
-		log.Warningf("S3_BUCKET is empty. No data will be written")
-		return nil // use as dry run for now
+	failed := 0
+	for _, target := range targets {
+		if err := exportTo(target, items, c); err != nil {
+			log.Errorf("export to %s failed: %s", export.Redact(target), err)
+			failed++
+			continue
+		}
+		log.Infof("Exported %d containers as %d items to %s", len(batch.Containers), len(items), export.Redact(target))
+	}
+	if failed > 0 {
+		return fmt.Errorf("export failed for %d of %d targets", failed, len(targets))
 	}
+	if skipped > 0 {
+		return fmt.Errorf("%d containers could not be inspected and were not exported", skipped)
+	}
+	return nil
+}
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(S3_REGION),
-	})
+// exportTo writes items to the sink for target.
+func exportTo(target string, items []export.Item, c *v2.Context) error {
+	sink, err := export.OpenSink(target, c)
 	if err != nil {
 		return err
 	}
-
-	s3client := s3.New(sess)
-
-	// Write data to S3
-	input := &s3.PutObjectInput{
-		Bucket: aws.String(S3_BUCKET),
-		Key:    aws.String(filename),
-		Body:   bytes.NewReader(data),
+	failed := 0
+	for _, item := range items {
+		if err := sink.Put(item); err != nil {
+			log.Errorf("failed to export %s: %s", item.Key, err)
+			failed++
+		}
 	}
-	_, err = s3client.PutObject(input)
-	if err != nil {
+	if err := sink.Close(); err != nil {
 		return err
 	}
-
-	log.Infof("Wrote %d bytes to %s\n", len(data), s3FullPath)
-
+	if failed > 0 {
+		return fmt.Errorf("%d of %d items could not be written", failed, len(items))
+	}
 	return nil
 }
@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"strings"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+func filterFlag() v2.Flag {
+	return &v2.StringSliceFlag{
+		Name:  "filter",
+		Usage: "Only include containers matching key=value (repeatable; keys: " + strings.Join(inspector.FilterKeys, ", ") + ")",
+	}
+}
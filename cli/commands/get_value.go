@@ -12,6 +12,10 @@ func GetValue() *v2.Command {
 	return &v2.Command{
 		Name:  "get",
 		Usage: "returns a plain value suitable for scripting",
+		Flags: append([]v2.Flag{
+			queryFlag(),
+			templateFlag("format", "f"),
+		}, decodeFlags()...),
 		Action: func(c *v2.Context) error {
 			var containerId, varName string
 
@@ -25,9 +29,9 @@ func GetValue() *v2.Command {
 				return v2.ShowSubcommandHelp(c)
 			}
 
-			ins, err := inspector.New()
+			ins, err := newInspector(c)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
 
 			val, err := ins.GetValue(containerId, varName)
@@ -35,6 +39,30 @@ func GetValue() *v2.Command {
 				log.Fatalf("unable to get value: %s", err)
 			}
 
+			dec, err := valueDecoder(c)
+			if err != nil {
+				return err
+			}
+			if dec != nil {
+				if val, err = dec(varName, val); err != nil {
+					log.Fatalf("unable to decode %s: %s", varName, err)
+				}
+			}
+
+			doc := getResult{Container: containerId, Key: varName, Value: val}
+			if ok, err := printQuery(c, doc); ok {
+				return err
+			}
+
+			if text := c.String("template"); text != "" {
+				ctr, err := ins.Inspect(containerId)
+				if err != nil {
+					log.Fatal(err)
+				}
+				_, err = printTemplate(text, []inspector.Container{ctr}, varName)
+				return err
+			}
+
 			if val == "" {
 				fmt.Println("<empty>")
 				return nil
@@ -45,3 +73,10 @@ func GetValue() *v2.Command {
 		},
 	}
 }
+
+// getResult is the --query document for get.
+type getResult struct {
+	Container string `json:"container"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+// newInspectors returns one Inspector per --host, or a single Inspector
+// for the default daemon when no hosts are configured.
+func newInspectors(c *v2.Context) ([]inspector.Inspector, error) {
+	hosts := c.StringSlice("host")
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+
+	inspectors := make([]inspector.Inspector, 0, len(hosts))
+	for _, host := range hosts {
+		ins, err := inspector.NewForHost(host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create docker inspector for %q: %w", host, err)
+		}
+		inspectors = append(inspectors, ins)
+	}
+	return inspectors, nil
+}
+
+// newInspector returns the Inspector for commands that work on a single
+// container and so accept at most one --host.
+func newInspector(c *v2.Context) (inspector.Inspector, error) {
+	hosts := c.StringSlice("host")
+	if len(hosts) > 1 {
+		return nil, fmt.Errorf("%s works on a single daemon, got %d --host flags", c.Command.Name, len(hosts))
+	}
+	host := ""
+	if len(hosts) == 1 {
+		host = hosts[0]
+	}
+	ins, err := inspector.NewForHost(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker inspector for %q: %w", host, err)
+	}
+	return ins, nil
+}
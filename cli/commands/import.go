@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	v2 "github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cmattoon/dockerenv/pkg/compose"
+	"github.com/cmattoon/dockerenv/pkg/dotenv"
+	"github.com/cmattoon/dockerenv/pkg/export"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+func Import() *v2.Command {
+	return &v2.Command{
+		Name:  "import",
+		Usage: "reads a container's environment back from an export",
+		Description: `--from is an export document (--format json) or the variables of one
+container written by --format kv:
+
+   dockerenv import --from ssm:///prod/shop/api
+   dockerenv import --from s3://backups/envs/dockerenv.json -c api --format compose
+   dockerenv import --from ./out/shop/api --format docker -o api.env`,
+		Flags: []v2.Flag{
+			&v2.StringFlag{
+				Name:  "from",
+				Usage: "Where to read from: a file or directory, file:///path, s3://bucket/key, s3://bucket/prefix/ or ssm:///prefix/path",
+			},
+			&v2.StringFlag{
+				Name:    "container-id",
+				Aliases: []string{"id", "c"},
+				Usage:   "The container to read from an export of several: an ID prefix, a name or a path",
+			},
+			&v2.StringFlag{
+				Name:  "format",
+				Value: "dotenv",
+				Usage: "dotenv (a compose .env file), compose (an environment: block) or docker (a docker run --env-file)",
+			},
+			&v2.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Write to this file instead of stdout",
+			},
+			&v2.BoolFlag{
+				Name:  "container-only",
+				Usage: "Skip variables inherited from the image (PATH, HOME, ...)",
+			},
+		},
+		Action: func(c *v2.Context) error {
+			from := c.String("from")
+			if from == "" {
+				fmt.Println("Must specify --from")
+				return v2.ShowSubcommandHelp(c)
+			}
+			format := c.String("format")
+			if format != "dotenv" && format != "compose" && format != "docker" {
+				return fmt.Errorf("unknown import format '%s' (valid: dotenv, compose, docker)", format)
+			}
+
+			ctr, err := export.Load(from, c)
+			if err != nil {
+				return err
+			}
+			var env []inspector.EnvVar
+			for _, ev := range ctr.Env {
+				if c.Bool("container-only") && ev.Source == inspector.SourceImage {
+					continue
+				}
+				env = append(env, ev)
+			}
+
+			var data []byte
+			switch format {
+			case "compose":
+				data, err = yaml.Marshal(struct {
+					Environment yaml.MapSlice `yaml:"environment"`
+				}{compose.Environment(env)})
+			case "docker":
+				data, err = dotenv.Marshal(env, dotenv.Docker)
+			default:
+				data, err = dotenv.Marshal(env, dotenv.Compose)
+			}
+			if err != nil {
+				return err
+			}
+			if out := c.String("output"); out != "" {
+				return ioutil.WriteFile(out, data, 0600)
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		},
+	}
+}
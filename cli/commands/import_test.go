@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/envdoc"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+func TestImportPicksContainer(t *testing.T) {
+	dir := t.TempDir()
+	doc := envdoc.New()
+	doc.Add(inspector.Container{ID: "aaa111aaa111", Name: "/api", Host: "local", Env: []inspector.EnvVar{
+		{Name: "LOG_LEVEL", Value: "debug"},
+	}}, "shop/api", nil)
+	doc.Add(inspector.Container{ID: "bbb222bbb222", Name: "/worker", Host: "local", Env: []inspector.EnvVar{
+		{Name: "QUEUE", Value: "jobs"},
+	}}, "shop/worker", nil)
+	data, err := doc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := filepath.Join(dir, "dockerenv.json")
+	if err := ioutil.WriteFile(from, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (string, error) {
+		out := filepath.Join(dir, "out.env")
+		app := &v2.App{Name: "dockerenv", Commands: []*v2.Command{Import()}}
+		err := app.Run(append([]string{"dockerenv", "import", "--from", from, "-o", out}, args...))
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadFile(out)
+		return string(data), err
+	}
+
+	for _, args := range [][]string{{"-c", "worker"}, {"--id", "bbb2"}, {"--container-id", "shop/worker"}} {
+		got, err := run(args...)
+		if err != nil {
+			t.Errorf("import %v: %s", args, err)
+			continue
+		}
+		if got != "QUEUE=jobs\n" {
+			t.Errorf("import %v wrote %q, want worker's environment", args, got)
+		}
+	}
+
+	if _, err := run(); err == nil || !strings.Contains(err.Error(), "--container-id") {
+		t.Errorf("import without a container returned %v, want a hint to use --container-id", err)
+	}
+}
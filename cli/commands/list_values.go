@@ -16,44 +16,132 @@ func ListValues() *v2.Command {
 	return &v2.Command{
 		Name:  "list",
 		Usage: "lists environment variables",
+		Flags: append([]v2.Flag{
+			&v2.BoolFlag{
+				Name:    "all",
+				Aliases: []string{"all-containers", "a"},
+				Usage:   "List the environment of every running container",
+			},
+			filterFlag(),
+			queryFlag(),
+			templateFlag("format", "f"),
+		}, decodeFlags()...),
 		Action: func(c *v2.Context) error {
+			all := c.Bool("all")
 			var containerId string
-			if containerId = c.String("container-id"); containerId == "" {
-				fmt.Println("Must specify --container-id")
+			if containerId = c.String("container-id"); containerId == "" && !all {
+				fmt.Println("Must specify --container-id or --all")
 				return v2.ShowSubcommandHelp(c)
 			}
 
-			ins, err := inspector.New()
+			inspectors, err := newInspectors(c)
 			if err != nil {
 				log.Fatal(err)
 			}
 
-			allValues, err := ins.GetAllValues(containerId)
-			if err != nil {
-				log.Fatal(err)
+			var containers []inspector.Container
+			if all {
+				for _, ins := range inspectors {
+					list, err := ins.ListContainers(c.StringSlice("filter")...)
+					if err != nil {
+						log.Fatal(err)
+					}
+					for _, summary := range list {
+						ctr, err := ins.Inspect(summary.ID)
+						if err != nil {
+							log.Error(err)
+							continue
+						}
+						containers = append(containers, ctr)
+					}
+				}
+			} else {
+				ctr, err := inspectors[0].Inspect(containerId)
+				if err != nil {
+					log.Fatal(err)
+				}
+				containers = append(containers, ctr)
 			}
 
-			maxlen := 0
-			for k, _ := range allValues {
-				if len(k) > maxlen {
-					maxlen = len(k)
+			dec, err := valueDecoder(c)
+			if err != nil {
+				return err
+			}
+			if dec != nil {
+				for _, ctr := range containers {
+					for i, ev := range ctr.Env {
+						v, err := dec(ev.Name, ev.Value)
+						if err != nil {
+							log.Warningf("%s: unable to decode %s: %s", ctr.Name, ev.Name, err)
+							continue
+						}
+						ctr.Env[i].Value = v
+					}
 				}
 			}
 
-			tlen, _, _ := terminal.GetSize(syscall.Stdout)
-			vlen := tlen - maxlen - 6
-			for k, v := range allValues {
-				s := mbsubstr(v, 0, vlen)
-				if len(v) > len(s) {
-					s = mbsubstr(s, 0, vlen-3) + "..."
+			doc := make([]listEntry, 0, len(containers))
+			for _, ctr := range containers {
+				doc = append(doc, newListEntry(ctr))
+			}
+			if ok, err := printQuery(c, doc); ok {
+				return err
+			}
+			if ok, err := printTemplate(c.String("template"), containers, ""); ok {
+				return err
+			}
+
+			for i, ctr := range containers {
+				if all {
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Printf("# %s (%s)\n", ctr.Name, ctr.ShortID())
 				}
-				fmt.Printf("%-*s    %s\n", maxlen, k, s)
+				printValues(ctr.Env)
 			}
 			return nil
 		},
 	}
 }
 
+// listEntry is the --query document for a single container. The
+// environment is keyed by name so expressions like env.LOG_LEVEL work.
+type listEntry struct {
+	inspector.Container
+	Env map[string]string `json:"env"`
+}
+
+func newListEntry(ctr inspector.Container) listEntry {
+	entry := listEntry{Container: ctr, Env: map[string]string{}}
+	for _, ev := range ctr.Env {
+		entry.Env[ev.Name] = ev.Value
+	}
+	return entry
+}
+
+func printValues(env []inspector.EnvVar) {
+	maxlen := 0
+	for _, ev := range env {
+		if len(ev.Name) > maxlen {
+			maxlen = len(ev.Name)
+		}
+	}
+
+	tlen, _, _ := terminal.GetSize(syscall.Stdout)
+	vlen := tlen - maxlen - 6
+	for _, ev := range env {
+		s := ev.Value
+		if tlen > 0 && vlen > 3 {
+			s = mbsubstr(ev.Value, 0, vlen)
+			if len(ev.Value) > len(s) {
+				s = mbsubstr(s, 0, vlen-3) + "..."
+			}
+		}
+		fmt.Printf("%-*s    %s\n", maxlen, ev.Name, s)
+	}
+}
+
 func mbsubstr(s string, from, length int) string {
 	//create array like string view
 	wb := []string{}
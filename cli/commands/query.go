@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"os"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/query"
+)
+
+func queryFlag() v2.Flag {
+	return &v2.StringFlag{
+		Name:    "query",
+		Aliases: []string{"q"},
+		Usage:   "JMESPath expression applied to the result document before printing",
+	}
+}
+
+// printQuery applies --query to doc and prints the result. It returns false
+// when no query was given, in which case the caller prints normally.
+func printQuery(c *v2.Context, doc interface{}) (bool, error) {
+	expr := c.String("query")
+	if expr == "" {
+		return false, nil
+	}
+	result, err := query.Apply(expr, doc)
+	if err != nil {
+		return true, err
+	}
+	return true, query.Print(os.Stdout, result)
+}
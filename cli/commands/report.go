@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/report"
+)
+
+func Report() *v2.Command {
+	return &v2.Command{
+		Name:  "report",
+		Usage: "writes a review report of every host's containers, environment, certificates and findings",
+		Flags: []v2.Flag{
+			&v2.StringFlag{
+				Name:  "format",
+				Value: "html",
+				Usage: "html (a single self-contained file) or md",
+			},
+			&v2.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Write the report to this file instead of stdout",
+			},
+			&v2.IntFlag{
+				Name:  "warn-days",
+				Value: 30,
+				Usage: "Flag certificates that expire within this many days",
+			},
+			filterFlag(),
+			secretPatternFlag(),
+			valuePolicyFlag(),
+		},
+		Action: func(c *v2.Context) error {
+			format := c.String("format")
+			if format != "html" && format != "md" {
+				return fmt.Errorf("unknown report format '%s' (valid: html, md)", format)
+			}
+			cls, err := newClassifier(c)
+			if err != nil {
+				return err
+			}
+			policy, err := valuePolicy(c)
+			if err != nil {
+				return err
+			}
+			opts := report.Options{
+				Classifier: cls,
+				Policy:     policy,
+				WarnDays:   c.Int("warn-days"),
+				Now:        time.Now(),
+			}
+
+			inspectors, err := newInspectors(c)
+			if err != nil {
+				return err
+			}
+			r := report.New(opts)
+			for _, ins := range inspectors {
+				list, err := ins.ListContainers(c.StringSlice("filter")...)
+				if err != nil {
+					return err
+				}
+				var containers []inspector.Container
+				for _, item := range list {
+					ctr, err := ins.Inspect(item.ID)
+					if err != nil {
+						log.Error(err)
+						continue
+					}
+					containers = append(containers, ctr)
+				}
+				r.AddHost(ins.Host(), containers, opts)
+			}
+
+			var data []byte
+			if format == "md" {
+				data = r.Markdown()
+			} else if data, err = r.HTML(); err != nil {
+				return err
+			}
+			if out := c.String("output"); out != "" {
+				return ioutil.WriteFile(out, data, 0600)
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		},
+	}
+}
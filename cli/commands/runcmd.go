@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/runcmd"
+)
+
+func RunCmd() *v2.Command {
+	return &v2.Command{
+		Name:  "runcmd",
+		Usage: "prints a docker run command that recreates a container",
+		Flags: []v2.Flag{
+			&v2.StringFlag{
+				Name:  "env-file",
+				Usage: "Write the environment to this file (docker dialect) and pass it with --env-file",
+			},
+			&v2.BoolFlag{
+				Name:  "reveal",
+				Usage: "Write secret values instead of reading them from the caller's environment",
+			},
+			secretPatternFlag(),
+		},
+		Action: func(c *v2.Context) error {
+			containerId := c.String("container-id")
+			if containerId == "" {
+				fmt.Println("Must specify --container-id")
+				return v2.ShowSubcommandHelp(c)
+			}
+
+			s, raw, err := inspectSpec(c, containerId)
+			if err != nil {
+				return err
+			}
+
+			opts := runcmd.Options{EnvFile: c.String("env-file")}
+			if !c.Bool("reveal") {
+				if opts.Classifier, err = newClassifier(c); err != nil {
+					return err
+				}
+			}
+			cmd, err := runcmd.Generate(raw, s, opts)
+			if err != nil {
+				return err
+			}
+			if opts.EnvFile != "" {
+				if err := ioutil.WriteFile(opts.EnvFile, cmd.EnvFile, 0600); err != nil {
+					return err
+				}
+			}
+			fmt.Print(cmd.String())
+			return nil
+		},
+	}
+}
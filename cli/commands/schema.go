@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/envdoc"
+)
+
+func Schema() *v2.Command {
+	return &v2.Command{
+		Name:  "schema",
+		Usage: "prints the JSON Schema for export --format json",
+		Action: func(c *v2.Context) error {
+			_, err := os.Stdout.Write(envdoc.Schema)
+			return err
+		},
+		Subcommands: []*v2.Command{
+			{
+				Name:      "validate",
+				Usage:     "validates an exported JSON document against the schema",
+				ArgsUsage: "FILE (or - for stdin)",
+				Action: func(c *v2.Context) error {
+					if c.NArg() != 1 {
+						fmt.Println("Must specify a file")
+						return v2.ShowSubcommandHelp(c)
+					}
+					var data []byte
+					var err error
+					if name := c.Args().First(); name == "-" {
+						data, err = ioutil.ReadAll(os.Stdin)
+					} else {
+						data, err = ioutil.ReadFile(name)
+					}
+					if err != nil {
+						return err
+					}
+					if err := envdoc.Validate(data); err != nil {
+						return err
+					}
+					fmt.Printf("%s: valid (schemaVersion %s)\n", c.Args().First(), envdoc.SchemaVersion)
+					return nil
+				},
+			},
+		},
+	}
+}
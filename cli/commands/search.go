@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+func Search() *v2.Command {
+	return &v2.Command{
+		Name:   "search",
+		Usage:  "finds containers by variable name, value or value hash",
+		Action: searchAction,
+		Flags: []v2.Flag{
+			filterFlag(),
+			&v2.StringFlag{
+				Name:    "key",
+				Aliases: []string{"k"},
+				Usage:   "Regular expression matched against variable names",
+			},
+			&v2.StringFlag{
+				Name:  "value",
+				Usage: "Regular expression matched against variable values",
+			},
+			&v2.StringFlag{
+				Name:  "hash",
+				Usage: "SHA-256 (hex) of the exact value to find, e.g. from `printf %s \"$v\" | sha256sum`",
+			},
+			&v2.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "table",
+				Usage:   "The output format (table, json)",
+			},
+			valuePolicyFlag(),
+		},
+	}
+}
+
+// SearchResult is a single matching variable. The value is shown
+// according to --value-policy.
+type SearchResult struct {
+	Host      string `json:"host"`
+	Container string `json:"container"`
+	Name      string `json:"name"`
+	Image     string `json:"image"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+}
+
+func searchAction(c *v2.Context) error {
+	var keyRe, valueRe *regexp.Regexp
+	var err error
+
+	if k := c.String("key"); k != "" {
+		if keyRe, err = regexp.Compile(k); err != nil {
+			return fmt.Errorf("invalid --key pattern: %w", err)
+		}
+	}
+	if v := c.String("value"); v != "" {
+		if valueRe, err = regexp.Compile(v); err != nil {
+			return fmt.Errorf("invalid --value pattern: %w", err)
+		}
+	}
+	hash := strings.ToLower(c.String("hash"))
+	policy, err := valuePolicy(c)
+	if err != nil {
+		return err
+	}
+
+	if keyRe == nil && valueRe == nil && hash == "" {
+		fmt.Println("Must specify at least one of --key, --value or --hash")
+		return v2.ShowSubcommandHelp(c)
+	}
+
+	inspectors, err := newInspectors(c)
+	if err != nil {
+		return err
+	}
+
+	selected := c.String("container-id")
+	results := []SearchResult{}
+	for _, ins := range inspectors {
+		containers, err := ins.ListContainers(c.StringSlice("filter")...)
+		if err != nil {
+			return err
+		}
+		for _, summary := range containers {
+			if selected != "" && !matchesContainer(summary, selected) {
+				continue
+			}
+			ctr, err := ins.Inspect(summary.ID)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			for _, ev := range ctr.Env {
+				if keyRe != nil && !keyRe.MatchString(ev.Name) {
+					continue
+				}
+				if valueRe != nil && !valueRe.MatchString(ev.Value) {
+					continue
+				}
+				if hash != "" && secrets.Hash(ev.Value) != hash {
+					continue
+				}
+				results = append(results, SearchResult{
+					Host:      ctr.Host,
+					Container: ctr.ShortID(),
+					Name:      ctr.Name,
+					Image:     ctr.Image,
+					Key:       ev.Name,
+					Value:     policy.Apply(ev.Value),
+				})
+			}
+		}
+	}
+
+	switch c.String("output") {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "HOST\tCONTAINER\tNAME\tIMAGE\tKEY\tVALUE")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Host, r.Container, r.Name, r.Image, r.Key, r.Value)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", c.String("output"))
+	}
+}
+
+// matchesContainer reports whether a container is selected by an ID
+// prefix or exact name.
+func matchesContainer(ctr inspector.Container, selector string) bool {
+	return strings.HasPrefix(ctr.ID, selector) || ctr.Name == strings.TrimPrefix(selector, "/")
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+func secretPatternFlag() v2.Flag {
+	return &v2.StringSliceFlag{
+		Name:  "secret-pattern",
+		Usage: "Regular expression for names of secret variables (repeatable; replaces the defaults)",
+	}
+}
+
+// newClassifier builds the secret classifier from --secret-pattern.
+func newClassifier(c *v2.Context) (*secrets.Classifier, error) {
+	return secrets.NewClassifier(c.StringSlice("secret-pattern")...)
+}
+
+func valuePolicyFlag() v2.Flag {
+	return &v2.StringFlag{
+		Name:  "value-policy",
+		Value: string(secrets.PolicyMask),
+		Usage: "How secret values are shown: mask, hash (a keyed fingerprint; set DOCKERENV_HASH_KEY to compare runs) or reveal",
+	}
+}
+
+// valuePolicy returns the policy selected with --value-policy.
+func valuePolicy(c *v2.Context) (secrets.Policy, error) {
+	return secrets.ParsePolicy(c.String("value-policy"))
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/shell"
+)
+
+func ShellEnv() *v2.Command {
+	return &v2.Command{
+		Name:  "shellenv",
+		Usage: "prints shell statements that reproduce a container's environment",
+		Description: `Evaluate the output to load the environment into the current shell:
+
+   eval "$(dockerenv -c api shellenv)"
+   dockerenv -c api shellenv --shell fish | source
+   dockerenv -c api shellenv --shell powershell | Invoke-Expression`,
+		Flags: []v2.Flag{
+			&v2.StringFlag{
+				Name:  "shell",
+				Usage: "bash, zsh, fish or powershell (default: from $SHELL)",
+			},
+			&v2.StringSliceFlag{
+				Name:  "include",
+				Usage: "Only variables whose name matches this glob (repeatable)",
+			},
+			&v2.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "Skip variables whose name matches this glob (repeatable)",
+			},
+			&v2.BoolFlag{
+				Name:  "container-only",
+				Usage: "Skip variables inherited from the image (PATH, HOME, ...)",
+			},
+			&v2.StringSliceFlag{
+				Name:  "rename-prefix",
+				Usage: "Rename variables starting with OLD to start with NEW, as OLD=NEW (repeatable)",
+			},
+			&v2.BoolFlag{
+				Name:  "unset",
+				Usage: "Print statements that remove the variables again",
+			},
+		},
+		Action: func(c *v2.Context) error {
+			containerId := c.String("container-id")
+			if containerId == "" {
+				fmt.Println("Must specify --container-id")
+				return v2.ShowSubcommandHelp(c)
+			}
+
+			shellName := c.String("shell")
+			if shellName == "" {
+				shellName = filepath.Base(os.Getenv("SHELL"))
+			}
+			sh, err := shell.Parse(shellName)
+			if err != nil {
+				if c.String("shell") != "" {
+					return err
+				}
+				sh = shell.Bash
+			}
+
+			renames, err := parseRenames(c.StringSlice("rename-prefix"))
+			if err != nil {
+				return err
+			}
+			for _, pattern := range append(c.StringSlice("include"), c.StringSlice("exclude")...) {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+				}
+			}
+
+			ins, err := newInspector(c)
+			if err != nil {
+				return err
+			}
+			ctr, err := ins.Inspect(containerId)
+			if err != nil {
+				return err
+			}
+
+			for _, ev := range ctr.Env {
+				if c.Bool("container-only") && ev.Source == inspector.SourceImage {
+					continue
+				}
+				if !selected(ev.Name, c.StringSlice("include"), c.StringSlice("exclude")) {
+					continue
+				}
+				name := renames.apply(ev.Name)
+				if !sh.ValidName(name) {
+					log.Warningf("skipping %s, which %s can't set", name, sh)
+					continue
+				}
+				if c.Bool("unset") {
+					fmt.Println(sh.Unset(name))
+				} else {
+					fmt.Println(sh.Export(name, ev.Value))
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// selected reports whether name matches one of include (or include is
+// empty) and none of exclude.
+func selected(name string, include, exclude []string) bool {
+	for _, pattern := range exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type prefixRenames [][2]string
+
+// parseRenames parses OLD=NEW prefix pairs. NEW may be empty to strip a
+// prefix.
+func parseRenames(specs []string) (prefixRenames, error) {
+	var r prefixRenames
+	for _, spec := range specs {
+		x := strings.SplitN(spec, "=", 2)
+		if len(x) != 2 || x[0] == "" {
+			return nil, fmt.Errorf("invalid --rename-prefix '%s': expected OLD=NEW", spec)
+		}
+		r = append(r, [2]string{x[0], x[1]})
+	}
+	return r, nil
+}
+
+// apply renames name with the first matching prefix.
+func (r prefixRenames) apply(name string) string {
+	for _, pair := range r {
+		if strings.HasPrefix(name, pair[0]) {
+			return pair[1] + strings.TrimPrefix(name, pair[0])
+		}
+	}
+	return name
+}
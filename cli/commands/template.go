@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"os"
+
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/templates"
+)
+
+func templateFlag(aliases ...string) v2.Flag {
+	return &v2.StringFlag{
+		Name:    "template",
+		Aliases: aliases,
+		Usage:   "Render each container with a Go template, e.g. '{{.Name}} {{.Get \"LOG_LEVEL\"}}'",
+	}
+}
+
+// printTemplate renders every container through --template. It returns
+// false when no template was given. Key and Value are filled in when a
+// single variable was selected.
+func printTemplate(text string, containers []inspector.Container, key string) (bool, error) {
+	if text == "" {
+		return false, nil
+	}
+	t, err := templates.Parse(text)
+	if err != nil {
+		return true, err
+	}
+	for _, ctr := range containers {
+		d := templates.NewData(ctr)
+		if key != "" {
+			d.Key = key
+			d.Value = d.Get(key)
+		}
+		if err := t.Execute(os.Stdout, d); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
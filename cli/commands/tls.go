@@ -12,7 +12,7 @@ import (
 
 	v2 "github.com/urfave/cli/v2"
 
-	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/certs"
 )
 
 func TLS() *v2.Command {
@@ -36,7 +36,7 @@ func TLS() *v2.Command {
 			{
 				Name:  "verify",
 				Usage: "equivalent to 'openssl x509 -noout -text'",
-				Flags: []v2.Flag{
+				Flags: append([]v2.Flag{
 					&v2.StringFlag{
 						Name:  "cert",
 						Usage: "The name of the environment var containing the cert",
@@ -52,9 +52,10 @@ func TLS() *v2.Command {
 					&v2.BoolFlag{
 						Name:    "b64decode",
 						Aliases: []string{"b64", "d"},
-						Usage:   "Apply base64 decoding to the raw value",
+						Usage:   "Apply base64 decoding to the raw value (same as --decode b64)",
 					},
-				},
+					queryFlag(),
+				}, decodeFlags()...),
 				Action: tlsVerifyAction,
 			},
 		},
@@ -76,9 +77,9 @@ func tlsVerifyAction(c *v2.Context) (err error) {
 		return v2.ShowSubcommandHelp(c)
 	}
 
-	i, err := inspector.New()
+	i, err := newInspector(c)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	tlsCertPEM, err := i.GetValue(containerId, tlsCertVar)
@@ -100,16 +101,52 @@ func tlsVerifyAction(c *v2.Context) (err error) {
 		log.Printf("with CA: %s", tlsCAPEM)
 	}
 
-	cert, err := tls.X509KeyPair(fixup("cert", tlsCertPEM), fixup("key", tlsKeyPEM))
+	var certPEM, keyPEM []byte
+	var extra []string
+	if c.Bool("b64decode") {
+		extra = append(extra, "b64")
+	}
+	dec, err := valueDecoder(c, extra...)
+	if err != nil {
+		return err
+	}
+	if dec == nil {
+		certPEM, keyPEM = fixup("cert", tlsCertPEM), fixup("key", tlsKeyPEM)
+	} else {
+		crt, err := dec(tlsCertVar, tlsCertPEM)
+		if err != nil {
+			log.Fatalf("failed to decode %s: %s", tlsCertVar, err)
+		}
+		key, err := dec(tlsKeyVar, tlsKeyPEM)
+		if err != nil {
+			log.Fatalf("failed to decode %s: %s", tlsKeyVar, err)
+		}
+		certPEM, keyPEM = []byte(crt), []byte(key)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
 		log.Fatalf("failed to create keypair from cert+key PEM: %s", err)
 	}
 
+	if c.String("query") != "" {
+		doc := []certs.Info{}
+		for i, crt := range cert.Certificate {
+			parsed, err := x509.ParseCertificate(crt)
+			if err != nil {
+				return fmt.Errorf("error parsing certificate %d: %w", i, err)
+			}
+			doc = append(doc, certs.NewInfo(i, parsed))
+		}
+		_, err = printQuery(c, doc)
+		return err
+	}
+
 	log.Printf("Loaded X509KeyPair with %d certs", len(cert.Certificate))
 	for i, crt := range cert.Certificate {
 		c, err := x509.ParseCertificate(crt)
 		if err != nil {
-			log.Println("error parsing certificate %d: %s", i, err)
+			log.Printf("error parsing certificate %d: %s", i, err)
 		}
 		// pretty print certificate
 		t := fmt.Sprintf("Certificate %d (CA: %v)", i, c.IsCA)
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	v2 "github.com/urfave/cli/v2"
+
+	"github.com/cmattoon/dockerenv/pkg/ui"
+)
+
+func UI() *v2.Command {
+	return &v2.Command{
+		Name:  "ui",
+		Usage: "browses containers and their environment in a terminal UI",
+		Flags: []v2.Flag{
+			filterFlag(),
+		},
+		Action: func(c *v2.Context) error {
+			inspectors, err := newInspectors(c)
+			if err != nil {
+				return err
+			}
+
+			screen, err := ui.NewTerminalScreen()
+			if err != nil {
+				return err
+			}
+			defer screen.Close()
+
+			return ui.New(screen, inspectors, c.StringSlice("filter")...).Run()
+		},
+	}
+}
@@ -0,0 +1,117 @@
+// Package certs describes X.509 certificates found in environment values.
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// Info is a serializable summary of a certificate.
+type Info struct {
+	Index          int       `json:"index"`
+	Subject        string    `json:"subject"`
+	Issuer         string    `json:"issuer"`
+	SerialNumber   string    `json:"serialNumber"`
+	SubjectKeyId   string    `json:"subjectKeyId"`
+	AuthorityKeyId string    `json:"authorityKeyId"`
+	IsCA           bool      `json:"isCA"`
+	NotBefore      time.Time `json:"notBefore"`
+	NotAfter       time.Time `json:"notAfter"`
+	DNSNames       []string  `json:"dnsNames"`
+	IPAddresses    []string  `json:"ipAddresses"`
+	KeyUsage       []string  `json:"keyUsage"`
+	ExtKeyUsage    []string  `json:"extKeyUsage"`
+}
+
+// NewInfo summarizes the i-th certificate of a chain.
+func NewInfo(i int, c *x509.Certificate) Info {
+	info := Info{
+		Index:          i,
+		Subject:        c.Subject.String(),
+		Issuer:         c.Issuer.String(),
+		SerialNumber:   c.SerialNumber.String(),
+		SubjectKeyId:   fmt.Sprintf("%x", c.SubjectKeyId),
+		AuthorityKeyId: fmt.Sprintf("%x", c.AuthorityKeyId),
+		IsCA:           c.IsCA,
+		NotBefore:      c.NotBefore,
+		NotAfter:       c.NotAfter,
+		DNSNames:       c.DNSNames,
+		KeyUsage:       KeyUsages(c.KeyUsage),
+	}
+	for _, ip := range c.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	for _, u := range c.ExtKeyUsage {
+		info.ExtKeyUsage = append(info.ExtKeyUsage, extKeyUsageNames[u])
+	}
+	return info
+}
+
+// ParsePEM returns info for every CERTIFICATE block in data. Other blocks,
+// such as private keys, are skipped.
+func ParsePEM(data []byte) ([]Info, error) {
+	var infos []Info
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return infos, fmt.Errorf("error parsing certificate %d: %w", len(infos), err)
+		}
+		infos = append(infos, NewInfo(len(infos), c))
+	}
+	return infos, nil
+}
+
+// Expired reports whether the certificate is no longer valid at t.
+func (i Info) Expired(t time.Time) bool {
+	return t.After(i.NotAfter)
+}
+
+// KeyUsages returns the names of the bits set in u.
+func KeyUsages(u x509.KeyUsage) []string {
+	var names []string
+	for bit := x509.KeyUsageDigitalSignature; bit <= x509.KeyUsageDecipherOnly; bit <<= 1 {
+		if u&bit != 0 {
+			names = append(names, keyUsageNames[bit])
+		}
+	}
+	return names
+}
+
+var keyUsageNames = map[x509.KeyUsage]string{
+	x509.KeyUsageDigitalSignature:  "KeyUsageDigitalSignature",
+	x509.KeyUsageContentCommitment: "KeyUsageContentCommitment",
+	x509.KeyUsageKeyEncipherment:   "KeyUsageKeyEncipherment",
+	x509.KeyUsageDataEncipherment:  "KeyUsageDataEncipherment",
+	x509.KeyUsageKeyAgreement:      "KeyUsageKeyAgreement",
+	x509.KeyUsageCertSign:          "KeyUsageCertSign",
+	x509.KeyUsageCRLSign:           "KeyUsageCRLSign",
+	x509.KeyUsageEncipherOnly:      "KeyUsageEncipherOnly",
+	x509.KeyUsageDecipherOnly:      "KeyUsageDecipherOnly",
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                            "Any",
+	x509.ExtKeyUsageServerAuth:                     "ServerAuth",
+	x509.ExtKeyUsageClientAuth:                     "ClientAuth",
+	x509.ExtKeyUsageCodeSigning:                    "CodeSigning",
+	x509.ExtKeyUsageEmailProtection:                "EmailProtection",
+	x509.ExtKeyUsageIPSECEndSystem:                 "IPSECEndSystem",
+	x509.ExtKeyUsageIPSECTunnel:                    "IPSECTunnel",
+	x509.ExtKeyUsageIPSECUser:                      "IPSECUser",
+	x509.ExtKeyUsageTimeStamping:                   "TimeStamping",
+	x509.ExtKeyUsageOCSPSigning:                    "OCSPSigning",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "MicrosoftServerGatedCrypto",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      "NetscapeServerGatedCrypto",
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "MicrosoftCommercialCodeSigning",
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "MicrosoftKernelCodeSigning",
+}
@@ -0,0 +1,143 @@
+// Package completion provides shell completion scripts and a small cache
+// so that completing container and variable names stays fast.
+package completion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrTimeout is returned by Get when fetch took too long.
+var ErrTimeout = errors.New("timed out")
+
+// Cache stores completion candidates on disk for a short time.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// NewCache returns a cache in the user's cache directory.
+func NewCache(ttl time.Duration) *Cache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return &Cache{Dir: filepath.Join(dir, "dockerenv", "completion"), TTL: ttl}
+}
+
+type entry struct {
+	Time   time.Time `json:"time"`
+	Values []string  `json:"values"`
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+func (c *Cache) load(key string) (entry, bool) {
+	var e entry
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil || json.Unmarshal(data, &e) != nil {
+		return e, false
+	}
+	return e, true
+}
+
+func (c *Cache) store(key string, values []string) {
+	data, err := json.Marshal(entry{Time: time.Now(), Values: values})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return
+	}
+	_ = ioutil.WriteFile(c.path(key), data, 0600)
+}
+
+// Get returns fresh cached values for key, or calls fetch. A fetch that
+// takes longer than timeout is abandoned with ErrTimeout, and stale cached
+// values (if any) are returned instead so that pressing tab never hangs.
+// The fetch still fills the cache if it finishes before the process exits;
+// callers that can't wait refresh the cache from another process.
+func (c *Cache) Get(key string, timeout time.Duration, fetch func() ([]string, error)) ([]string, error) {
+	cached, ok := c.load(key)
+	if ok && time.Since(cached.Time) < c.TTL {
+		return cached.Values, nil
+	}
+
+	type result struct {
+		values []string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		values, err := fetch()
+		if err == nil {
+			c.store(key, values)
+		}
+		done <- result{values, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return cached.Values, r.err
+		}
+		return r.values, nil
+	case <-time.After(timeout):
+		return cached.Values, fmt.Errorf("%w after %s", ErrTimeout, timeout)
+	}
+}
+
+// Script returns the completion script for a shell.
+func Script(shell, prog string) (string, error) {
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(bashScript, prog), nil
+	case "zsh":
+		return fmt.Sprintf(zshScript, prog), nil
+	case "fish":
+		return fmt.Sprintf(fishScript, prog), nil
+	}
+	return "", fmt.Errorf("unsupported shell '%s' (valid: bash, zsh, fish)", shell)
+}
+
+// Each script passes the words typed so far, ending with the (possibly
+// empty) word being completed, to the hidden `__complete` command.
+
+const bashScript = `# bash completion for %[1]s
+# eval "$(%[1]s completion bash)"
+_%[1]s_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    COMPREPLY=( $(compgen -W "$(%[1]s __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)" -- "$cur") )
+}
+complete -o default -F _%[1]s_complete %[1]s
+`
+
+const zshScript = `#compdef %[1]s
+# source <(%[1]s completion zsh)
+_%[1]s() {
+    local -a candidates
+    candidates=("${(@f)$(%[1]s __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    compadd -- "${candidates[@]}"
+}
+compdef _%[1]s %[1]s
+`
+
+const fishScript = `# fish completion for %[1]s
+# %[1]s completion fish | source
+function __%[1]s_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    %[1]s __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c %[1]s -f -a '(__%[1]s_complete)'
+`
@@ -0,0 +1,43 @@
+package completion
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetTimeout(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	values, err := c.Get("containers", 10*time.Millisecond, func() ([]string, error) {
+		<-release
+		return []string{"api"}, nil
+	})
+	if !errors.Is(err, ErrTimeout) || values != nil {
+		t.Errorf("Get = %v, %v; want ErrTimeout and no values", values, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get waited %s for a slow fetch", elapsed)
+	}
+}
+
+func TestGetCaches(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+	want := []string{"api", "worker"}
+	values, err := c.Get("containers", time.Second, func() ([]string, error) { return want, nil })
+	if err != nil || !reflect.DeepEqual(values, want) {
+		t.Fatalf("Get = %v, %v; want %v", values, err, want)
+	}
+
+	values, err = c.Get("containers", time.Second, func() ([]string, error) {
+		t.Error("fetched again instead of using the cache")
+		return nil, nil
+	})
+	if err != nil || !reflect.DeepEqual(values, want) {
+		t.Errorf("cached Get = %v, %v; want %v", values, err, want)
+	}
+}
@@ -0,0 +1,175 @@
+// Package compose renders a container spec as a docker-compose service.
+package compose
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/spec"
+)
+
+// Options controls how the service is written.
+type Options struct {
+	// Service is the name of the service; the container name if empty.
+	Service string
+	// EnvFile, when set, replaces the inline environment with a reference
+	// to this file. The caller writes the file.
+	EnvFile string
+}
+
+// File is a compose file holding one service.
+type File struct {
+	Services map[string]Service  `yaml:"services"`
+	Networks map[string]External `yaml:"networks,omitempty"`
+	Volumes  map[string]External `yaml:"volumes,omitempty"`
+}
+
+// External declares a network or volume that already exists, so compose
+// reuses it instead of creating a project-scoped one.
+type External struct {
+	External bool `yaml:"external"`
+}
+
+// Service is a compose service definition.
+type Service struct {
+	Image       string            `yaml:"image"`
+	Entrypoint  []string          `yaml:"entrypoint,omitempty"`
+	Command     []string          `yaml:"command,omitempty"`
+	User        string            `yaml:"user,omitempty"`
+	WorkingDir  string            `yaml:"working_dir,omitempty"`
+	EnvFile     []string          `yaml:"env_file,omitempty"`
+	Environment yaml.MapSlice     `yaml:"environment,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	Tmpfs       []string          `yaml:"tmpfs,omitempty"`
+	NetworkMode string            `yaml:"network_mode,omitempty"`
+	Networks    []string          `yaml:"networks,omitempty"`
+	Restart     string            `yaml:"restart,omitempty"`
+	Healthcheck *Healthcheck      `yaml:"healthcheck,omitempty"`
+}
+
+// Healthcheck is a compose healthcheck; durations use Go syntax ("1m30s"),
+// which compose accepts.
+type Healthcheck struct {
+	Test        []string `yaml:"test"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	StartPeriod string   `yaml:"start_period,omitempty"`
+	Retries     int      `yaml:"retries,omitempty"`
+}
+
+// Environment returns vars as a service's environment mapping, in order.
+func Environment(vars []inspector.EnvVar) yaml.MapSlice {
+	env := yaml.MapSlice{}
+	for _, ev := range vars {
+		env = append(env, yaml.MapItem{Key: ev.Name, Value: escape(ev.Value)})
+	}
+	return env
+}
+
+// Generate builds a compose file containing s as a single service.
+func Generate(s spec.Spec, opts Options) (*File, error) {
+	name := opts.Service
+	if name == "" {
+		name = s.Name
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no service name")
+	}
+
+	svc := Service{
+		Image:       escape(s.Image),
+		Entrypoint:  escapeAll(s.Entrypoint),
+		Command:     escapeAll(s.Cmd),
+		User:        escape(s.User),
+		WorkingDir:  escape(s.WorkingDir),
+		NetworkMode: escape(s.NetworkMode),
+		Networks:    s.Networks,
+		Restart:     s.Restart,
+	}
+	if len(s.Labels) > 0 {
+		svc.Labels = map[string]string{}
+		for k, v := range s.Labels {
+			svc.Labels[k] = escape(v)
+		}
+	}
+	if opts.EnvFile != "" {
+		svc.EnvFile = []string{escape(opts.EnvFile)}
+	} else {
+		svc.Environment = Environment(s.Env)
+	}
+
+	f := &File{Services: map[string]Service{}}
+	for _, p := range s.Ports {
+		svc.Ports = append(svc.Ports, escape(p.String()))
+	}
+	for _, m := range s.Mounts {
+		switch m.Type {
+		case "tmpfs":
+			svc.Tmpfs = append(svc.Tmpfs, escape(m.Target))
+			continue
+		case "volume":
+			if f.Volumes == nil {
+				f.Volumes = map[string]External{}
+			}
+			f.Volumes[m.Source] = External{External: true}
+		}
+		v := m.Source + ":" + m.Target
+		if m.ReadOnly {
+			v += ":ro"
+		}
+		svc.Volumes = append(svc.Volumes, escape(v))
+	}
+	for _, n := range s.Networks {
+		if f.Networks == nil {
+			f.Networks = map[string]External{}
+		}
+		f.Networks[n] = External{External: true}
+	}
+	if hc := s.Healthcheck; hc != nil {
+		svc.Healthcheck = &Healthcheck{
+			Test:        escapeAll(hc.Test),
+			Interval:    duration(hc.Interval),
+			Timeout:     duration(hc.Timeout),
+			StartPeriod: duration(hc.StartPeriod),
+			Retries:     hc.Retries,
+		}
+	}
+
+	f.Services[name] = svc
+	return f, nil
+}
+
+// Marshal encodes f as YAML.
+func (f *File) Marshal() ([]byte, error) {
+	return yaml.Marshal(f)
+}
+
+// escape protects s from compose, which interpolates $NAME in every
+// string value of the file.
+func escape(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+func escapeAll(words []string) []string {
+	if words == nil {
+		return nil
+	}
+	escaped := make([]string, len(words))
+	for i, w := range words {
+		escaped[i] = escape(w)
+	}
+	return escaped
+}
+
+func duration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -0,0 +1,346 @@
+// Package decode undoes the layers of encoding commonly applied to values
+// before they are put into environment variables.
+//
+// A chain is a comma separated list of steps, applied left to right:
+//
+//	b64         standard base64 (padding optional)
+//	b64url      URL-safe base64 (padding optional)
+//	hex         hexadecimal
+//	gzip        gzip decompression
+//	url         URL query unescaping (%XX and +)
+//	unescape    backslash escapes such as \n, \t and \"
+//	unquote     remove one pair of surrounding quotes
+//	json:PATH   the value at a dot separated PATH in a JSON document,
+//	            e.g. json:tls.cert or json:certs.0
+//
+// Auto guesses the chain instead.
+package decode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Step is one decoding operation.
+type Step struct {
+	Name string
+	fn   func(string) (string, error)
+}
+
+// Chain is a sequence of steps.
+type Chain []Step
+
+// Steps are the decoders available by name. json:PATH is handled by Parse.
+var Steps = map[string]func(string) (string, error){
+	"b64":      decodeBase64(base64.StdEncoding),
+	"b64url":   decodeBase64(base64.URLEncoding),
+	"hex":      decodeHex,
+	"gzip":     decodeGzip,
+	"url":      url.QueryUnescape,
+	"unescape": unescape,
+	"unquote":  unquote,
+}
+
+// Parse builds a chain from a spec such as "b64,gzip,json:tls.cert".
+func Parse(spec string) (Chain, error) {
+	var chain Chain
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "json:") {
+			chain = append(chain, jsonStep(strings.TrimPrefix(name, "json:")))
+			continue
+		}
+		fn, ok := Steps[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown decode step '%s' (valid: %s, json:PATH)", name, strings.Join(StepNames(), ", "))
+		}
+		chain = append(chain, Step{Name: name, fn: fn})
+	}
+	return chain, nil
+}
+
+// StepNames returns the sorted names of the available steps.
+func StepNames() []string {
+	names := make([]string, 0, len(Steps))
+	for name := range Steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply runs every step in order.
+func (c Chain) Apply(value string) (string, error) {
+	for _, step := range c {
+		v, err := step.fn(value)
+		if err != nil {
+			return value, fmt.Errorf("%s: %w", step.Name, err)
+		}
+		value = v
+	}
+	return value, nil
+}
+
+// String returns the chain in the form accepted by Parse.
+func (c Chain) String() string {
+	names := make([]string, len(c))
+	for i, step := range c {
+		names[i] = step.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// maxLayers bounds Auto so that pathological input can't loop forever.
+const maxLayers = 10
+
+var (
+	hexRe    = regexp.MustCompile(`^(0x)?([0-9a-fA-F]{2})+$`)
+	b64Re    = regexp.MustCompile(`^[A-Za-z0-9+/]+={0,2}$`)
+	b64urlRe = regexp.MustCompile(`^[A-Za-z0-9_-]+={0,2}$`)
+	urlRe    = regexp.MustCompile(`%[0-9a-fA-F]{2}`)
+)
+
+// Auto repeatedly detects and removes a layer of encoding until the value
+// is plain text, JSON without an embedded PEM block, or PEM. It returns the
+// decoded value and the chain it applied, which is empty when the value
+// was already plain.
+func Auto(value string) (string, Chain) {
+	var applied Chain
+	for i := 0; i < maxLayers; i++ {
+		step, ok := detect(value)
+		if !ok {
+			break
+		}
+		v, err := step.fn(value)
+		if err != nil || v == value {
+			break
+		}
+		value = v
+		applied = append(applied, step)
+	}
+	return value, applied
+}
+
+func detect(value string) (Step, bool) {
+	trimmed := strings.TrimSpace(value)
+	compact := strings.Join(strings.Fields(trimmed), "")
+
+	var doc interface{}
+	isJSON := (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) &&
+		json.Unmarshal([]byte(trimmed), &doc) == nil
+
+	switch {
+	case strings.HasPrefix(value, "\x1f\x8b"):
+		return Step{Name: "gzip", fn: decodeGzip}, true
+
+	case len(trimmed) >= 2 && (trimmed[0] == '"' || trimmed[0] == '\'') && trimmed[len(trimmed)-1] == trimmed[0]:
+		return Step{Name: "unquote", fn: unquote}, true
+
+	case isJSON:
+		if p, ok := findPEM(doc, ""); ok {
+			return jsonStep(p), true
+		}
+		return Step{}, false
+
+	case strings.Contains(value, `\n`) && !strings.Contains(value, "\n"),
+		strings.Contains(value, `\"`) && strings.Count(value, `\"`) == strings.Count(value, `"`):
+		return Step{Name: "unescape", fn: unescape}, true
+
+	case strings.Contains(trimmed, "-----BEGIN "):
+		return Step{}, false
+
+	case len(compact) >= 16 && hexRe.MatchString(compact):
+		if plausible(decodeHex(compact)) {
+			return Step{Name: "hex", fn: decodeHex}, true
+		}
+
+	case len(compact) >= 8 && b64Re.MatchString(compact):
+		if plausible(decodeBase64(base64.StdEncoding)(compact)) {
+			return Step{Name: "b64", fn: decodeBase64(base64.StdEncoding)}, true
+		}
+
+	case len(compact) >= 8 && b64urlRe.MatchString(compact):
+		if plausible(decodeBase64(base64.URLEncoding)(compact)) {
+			return Step{Name: "b64url", fn: decodeBase64(base64.URLEncoding)}, true
+		}
+	}
+
+	// Escapes inside a URL, such as a password in a DSN, are intentional.
+	if urlRe.MatchString(value) && !strings.Contains(value, "://") {
+		if v, err := url.QueryUnescape(value); err == nil && v != value {
+			return Step{Name: "url", fn: url.QueryUnescape}, true
+		}
+	}
+	return Step{}, false
+}
+
+// plausible reports whether a decoded value looks like the real thing:
+// gzip data or printable UTF-8 text.
+func plausible(v string, err error) bool {
+	if err != nil || v == "" {
+		return false
+	}
+	if strings.HasPrefix(v, "\x1f\x8b") {
+		return true
+	}
+	if !utf8.ValidString(v) {
+		return false
+	}
+	for _, r := range v {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// findPEM returns the path of the first string in doc containing a PEM block.
+func findPEM(doc interface{}, prefix string) (string, bool) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch v := doc.(type) {
+	case string:
+		return prefix, strings.Contains(v, "-----BEGIN ")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if p, ok := findPEM(v[k], join(k)); ok {
+				return p, true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if p, ok := findPEM(item, join(strconv.Itoa(i))); ok {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+func decodeBase64(enc *base64.Encoding) func(string) (string, error) {
+	return func(s string) (string, error) {
+		s = strings.Join(strings.Fields(s), "")
+		b, err := enc.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(s, "="))
+		return string(b), err
+	}
+}
+
+func decodeHex(s string) (string, error) {
+	s = strings.TrimPrefix(strings.Join(strings.Fields(s), ""), "0x")
+	b, err := hex.DecodeString(s)
+	return string(b), err
+}
+
+func decodeGzip(s string) (string, error) {
+	r, err := gzip.NewReader(bytes.NewReader([]byte(s)))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	return string(b), err
+}
+
+func unquote(s string) (string, error) {
+	t := strings.TrimSpace(s)
+	if len(t) >= 2 && (t[0] == '"' || t[0] == '\'') && t[len(t)-1] == t[0] {
+		return t[1 : len(t)-1], nil
+	}
+	return s, nil
+}
+
+// unescape interprets backslash escapes. Unknown escapes are kept as-is.
+func unescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '\\', '"', '\'', '/':
+			b.WriteByte(s[i])
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteString(`\u`)
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+func jsonStep(p string) Step {
+	return Step{
+		Name: "json:" + p,
+		fn: func(s string) (string, error) {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(s), &doc); err != nil {
+				return "", err
+			}
+			for _, key := range strings.Split(p, ".") {
+				if key == "" {
+					continue
+				}
+				switch v := doc.(type) {
+				case map[string]interface{}:
+					next, ok := v[key]
+					if !ok {
+						return "", fmt.Errorf("no key '%s'", key)
+					}
+					doc = next
+				case []interface{}:
+					i, err := strconv.Atoi(key)
+					if err != nil || i < 0 || i >= len(v) {
+						return "", fmt.Errorf("invalid index '%s'", key)
+					}
+					doc = v[i]
+				default:
+					return "", fmt.Errorf("cannot index %T with '%s'", doc, key)
+				}
+			}
+			if str, ok := doc.(string); ok {
+				return str, nil
+			}
+			b, err := json.Marshal(doc)
+			return string(b), err
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package decode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+const pemCert = "-----BEGIN CERTIFICATE-----\nMIIBszCCAVmgAwIBAgIUQ2E0\n-----END CERTIFICATE-----\n"
+
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func b64(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+func TestAuto(t *testing.T) {
+	doc := `{"host":"db","port":5432}`
+	nested, err := json.Marshal(map[string]interface{}{"tls": map[string]string{"cert": pemCert, "name": "api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, value, want, chain string
+	}{
+		{"base64 of gzip of JSON", b64(gzipped(t, doc)), doc, "b64,gzip"},
+		{"PEM nested in JSON", string(nested), pemCert, "json:tls.cert"},
+		{"base64 of PEM nested in JSON", b64(string(nested)), pemCert, "b64,json:tls.cert"},
+		{"b64url", base64.URLEncoding.EncodeToString([]byte("a value?>>with url-unsafe bytes")), "a value?>>with url-unsafe bytes", "b64url"},
+		{"hex", hex.EncodeToString([]byte("hello, world")), "hello, world", "hex"},
+		{"quoted", `"hello world"`, "hello world", "unquote"},
+		{"escaped PEM", `-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n`, "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n", "unescape"},
+		{"url", url.QueryEscape("a b&c=d"), "a b&c=d", "url"},
+		{"PEM", pemCert, pemCert, ""},
+		{"JSON without PEM", doc, doc, ""},
+	}
+	for _, tt := range tests {
+		got, chain := Auto(tt.value)
+		if got != tt.want || chain.String() != tt.chain {
+			t.Errorf("%s: Auto(%q) = %q, %q; want %q, %q", tt.name, tt.value, got, chain, tt.want, tt.chain)
+		}
+	}
+}
+
+func TestAutoLeavesPlainValues(t *testing.T) {
+	for _, value := range []string{
+		"", "debug", "deadbeef", "DEADBEEF", "cafebabe1234", "password", "username", "localhost",
+		"production", "abc123def456", "hunter2hunter2", "12345678", "v1.2.3",
+		"postgres://app:p%40ss@db/app", "a-b_c", "true",
+	} {
+		if got, chain := Auto(value); len(chain) > 0 {
+			t.Errorf("Auto(%q) decoded it with %s to %q", value, chain, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc := `{"tls":{"cert":"PEM"}}`
+	chain, err := Parse("b64, gzip ,json:tls.cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chain.String(); got != "b64,gzip,json:tls.cert" {
+		t.Errorf("String() = %q", got)
+	}
+	got, err := chain.Apply(b64(gzipped(t, doc)))
+	if err != nil || got != "PEM" {
+		t.Errorf("Apply = %q, %v; want PEM", got, err)
+	}
+
+	if _, err := Parse("b64,rot13"); err == nil {
+		t.Error("Parse accepted an unknown step")
+	}
+	if _, err := chain.Apply("not base64!"); err == nil {
+		t.Error("Apply accepted a value that isn't base64")
+	}
+}
@@ -0,0 +1,209 @@
+// Package dotenv reads and writes environment files in two dialects.
+//
+// Docker is the format of `docker run --env-file`: every line is
+// NAME=value taken literally, with no quoting or escaping, so values can't
+// contain newlines. Lines starting with # are comments.
+//
+// Compose is the .env format read by docker-compose and dotenv libraries.
+// Values that need it are quoted: single quotes are literal, double quotes
+// understand \n, \r, \t, \\ and \" escapes and "$$" for a literal "$",
+// since compose interpolates $NAME in double quoted and unquoted values.
+package dotenv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+// Dialect selects the quoting rules.
+type Dialect int
+
+const (
+	Docker Dialect = iota
+	Compose
+)
+
+// Dialects maps the names accepted by ParseDialect.
+var Dialects = map[string]Dialect{
+	"docker":  Docker,
+	"compose": Compose,
+}
+
+// ParseDialect returns the dialect with the given name.
+func ParseDialect(name string) (Dialect, error) {
+	if d, ok := Dialects[name]; ok {
+		return d, nil
+	}
+	return Docker, fmt.Errorf("unknown env file dialect '%s' (valid: docker, compose)", name)
+}
+
+func (d Dialect) String() string {
+	if d == Compose {
+		return "compose"
+	}
+	return "docker"
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// plain matches values that can be written without quotes in any dialect.
+var plain = regexp.MustCompile(`^[A-Za-z0-9_./:@,+%=^-]*$`)
+
+// Marshal encodes vars, one per line, in order.
+func Marshal(vars []inspector.EnvVar, d Dialect) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Write(&buf, vars, d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write encodes vars to w. It fails on values the dialect can't represent.
+func Write(w io.Writer, vars []inspector.EnvVar, d Dialect) error {
+	bw := bufio.NewWriter(w)
+	for _, v := range vars {
+		line, err := encode(v, d)
+		if err != nil {
+			return err
+		}
+		bw.WriteString(line)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+func encode(v inspector.EnvVar, d Dialect) (string, error) {
+	if !validName.MatchString(v.Name) {
+		return "", fmt.Errorf("%s: invalid variable name", v.Name)
+	}
+	if d == Docker {
+		if strings.ContainsAny(v.Value, "\n\r") {
+			return "", fmt.Errorf("%s: docker env files can't contain newlines (use the compose dialect)", v.Name)
+		}
+		return v.Name + "=" + v.Value, nil
+	}
+	return v.Name + "=" + Quote(v.Value), nil
+}
+
+// Quote returns value as written by the Compose dialect.
+func Quote(value string) string {
+	if plain.MatchString(value) {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '$':
+			b.WriteString("$$")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Parse reads a file written in dialect d. Variables without a value
+// ("NAME" on its own) are returned with an empty value.
+func Parse(r io.Reader, d Dialect) ([]inspector.EnvVar, error) {
+	var vars []inspector.EnvVar
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimLeft(s.Text(), " \t")
+		if d == Compose {
+			line = strings.TrimRight(line, " \t\r")
+			line = strings.TrimPrefix(line, "export ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		name := strings.TrimSpace(kv[0])
+		if !validName.MatchString(name) {
+			return nil, fmt.Errorf("line %d: invalid variable name '%s'", n, name)
+		}
+		value := ""
+		if len(kv) == 2 {
+			value = kv[1]
+			if d == Compose {
+				var err error
+				if value, err = unquote(value); err != nil {
+					return nil, fmt.Errorf("line %d: %s: %w", n, name, err)
+				}
+			}
+		}
+		vars = append(vars, inspector.EnvVar{Name: name, Value: value})
+	}
+	return vars, s.Err()
+}
+
+// unquote decodes a Compose dialect value.
+func unquote(v string) (string, error) {
+	v = strings.TrimLeft(v, " \t")
+	switch {
+	case strings.HasPrefix(v, "'"):
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		return v[1 : end+1], nil
+
+	case strings.HasPrefix(v, `"`):
+		var b strings.Builder
+		for i := 1; i < len(v); i++ {
+			c := v[i]
+			switch {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(v):
+				i++
+				switch v[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(v[i])
+				}
+			case c == '$' && i+1 < len(v) && v[i+1] == '$':
+				b.WriteByte('$')
+				i++
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double quote")
+
+	default:
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = v[:i]
+		}
+		return strings.ReplaceAll(strings.TrimSpace(v), "$$", "$"), nil
+	}
+}
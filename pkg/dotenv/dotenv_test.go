@@ -0,0 +1,85 @@
+package dotenv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+const pem = `-----BEGIN CERTIFICATE-----
+MIIBszCCAVmgAwIBAgIUQ8b1
+aGVsbG8gd29ybGQ=
+-----END CERTIFICATE-----
+`
+
+var values = []struct {
+	name  string
+	value string
+	// multiline values can't be written in the docker dialect.
+	multiline bool
+}{
+	{"empty", "", false},
+	{"plain", "postgres://db:5432/app", false},
+	{"spaces", "hello world", false},
+	{"surrounding spaces", "  padded  ", false},
+	{"hash", "#not-a-comment", false},
+	{"inline hash", "a # b", false},
+	{"single quote", "it's", false},
+	{"double quote", `say "hi"`, false},
+	{"both quotes", `it's "quoted"`, false},
+	{"dollar", "$HOME", false},
+	{"braces", "${HOME}/bin", false},
+	{"double dollar", "pa$$word", false},
+	{"backslash", `C:\path\to`, false},
+	{"escape-like", `\n is not a newline`, false},
+	{"tab", "a\tb", false},
+	{"newline", "line1\nline2", true},
+	{"crlf", "line1\r\nline2", true},
+	{"quote and newline", "it's\n$HOME", true},
+	{"pem", pem, true},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, d := range []Dialect{Compose, Docker} {
+		for _, tc := range values {
+			t.Run(d.String()+"/"+tc.name, func(t *testing.T) {
+				in := []inspector.EnvVar{{Name: "VAR", Value: tc.value}, {Name: "NEXT", Value: "x"}}
+				data, err := Marshal(in, d)
+				if d == Docker && tc.multiline {
+					if err == nil {
+						t.Fatalf("Marshal(%q) = %q, want an error", tc.value, data)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Marshal(%q): %v", tc.value, err)
+				}
+				out, err := Parse(bytes.NewReader(data), d)
+				if err != nil {
+					t.Fatalf("Parse(%q): %v", data, err)
+				}
+				if len(out) != 2 || out[0].Value != tc.value || out[1].Value != "x" {
+					t.Errorf("round trip of %q through %q gave %+v", tc.value, data, out)
+				}
+			})
+		}
+	}
+}
+
+func TestQuoteSingleLine(t *testing.T) {
+	for _, tc := range values {
+		if q := Quote(tc.value); strings.ContainsAny(q, "\r\n") {
+			t.Errorf("Quote(%q) = %q spans lines", tc.value, q)
+		}
+	}
+}
+
+func TestDockerRejectsNewlines(t *testing.T) {
+	for _, v := range []string{"a\nb", "a\rb", "a\r\nb"} {
+		if _, err := Marshal([]inspector.EnvVar{{Name: "VAR", Value: v}}, Docker); err == nil {
+			t.Errorf("Marshal(%q) in the docker dialect succeeded", v)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package ecs renders a container as an entry of an ECS task definition's
+// containerDefinitions.
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+	"github.com/cmattoon/dockerenv/pkg/spec"
+)
+
+// KeyValuePair is an environment entry.
+type KeyValuePair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Secret is an environment entry read from SSM or Secrets Manager.
+type Secret struct {
+	Name      string `json:"name"`
+	ValueFrom string `json:"valueFrom"`
+}
+
+// PortMapping is a published port.
+type PortMapping struct {
+	ContainerPort int    `json:"containerPort"`
+	HostPort      int    `json:"hostPort,omitempty"`
+	Protocol      string `json:"protocol"`
+}
+
+// ContainerDefinition holds the fields of an ECS container definition
+// that can be derived from a docker container.
+type ContainerDefinition struct {
+	Name              string            `json:"name"`
+	Image             string            `json:"image"`
+	Essential         bool              `json:"essential"`
+	EntryPoint        []string          `json:"entryPoint,omitempty"`
+	Command           []string          `json:"command,omitempty"`
+	WorkingDirectory  string            `json:"workingDirectory,omitempty"`
+	User              string            `json:"user,omitempty"`
+	Environment       []KeyValuePair    `json:"environment"`
+	Secrets           []Secret          `json:"secrets,omitempty"`
+	PortMappings      []PortMapping     `json:"portMappings,omitempty"`
+	DockerLabels      map[string]string `json:"dockerLabels,omitempty"`
+	Memory            int64             `json:"memory,omitempty"`
+	MemoryReservation int64             `json:"memoryReservation,omitempty"`
+	CPU               int64             `json:"cpu,omitempty"`
+}
+
+// Options controls how the definition is generated.
+type Options struct {
+	// Classifier picks the variables written as secrets. All variables
+	// are plain environment entries when it is nil.
+	Classifier *secrets.Classifier
+	// SecretPath returns the SSM parameter a secret variable is read from.
+	SecretPath func(name string) string
+}
+
+// Generate builds the definition of the container described by s. Only
+// variables set on the container are included; the image provides the
+// rest.
+func Generate(s spec.Spec, vars []inspector.EnvVar, opts Options) (*ContainerDefinition, error) {
+	def := &ContainerDefinition{
+		Name:             s.Name,
+		Image:            s.Image,
+		Essential:        true,
+		EntryPoint:       s.Entrypoint,
+		Command:          s.Cmd,
+		WorkingDirectory: s.WorkingDir,
+		User:             s.User,
+		Environment:      []KeyValuePair{},
+	}
+	if len(s.Labels) > 0 {
+		def.DockerLabels = s.Labels
+	}
+
+	for _, ev := range vars {
+		if ev.Source == inspector.SourceImage {
+			continue
+		}
+		if opts.Classifier != nil && opts.Classifier.IsSecret(ev.Name, ev.Value) {
+			if opts.SecretPath == nil {
+				return nil, fmt.Errorf("%s: no parameter path for secret", ev.Name)
+			}
+			def.Secrets = append(def.Secrets, Secret{Name: ev.Name, ValueFrom: opts.SecretPath(ev.Name)})
+			continue
+		}
+		def.Environment = append(def.Environment, KeyValuePair{Name: ev.Name, Value: ev.Value})
+	}
+	sort.Slice(def.Environment, func(i, j int) bool { return def.Environment[i].Name < def.Environment[j].Name })
+	sort.Slice(def.Secrets, func(i, j int) bool { return def.Secrets[i].Name < def.Secrets[j].Name })
+
+	for _, p := range s.Ports {
+		cp, err := strconv.Atoi(p.ContainerPort)
+		if err != nil {
+			return nil, fmt.Errorf("port %s: %w", p.ContainerPort, err)
+		}
+		pm := PortMapping{ContainerPort: cp, Protocol: p.Protocol}
+		if p.HostPort != "" {
+			if pm.HostPort, err = strconv.Atoi(p.HostPort); err != nil {
+				return nil, fmt.Errorf("port %s: %w", p.HostPort, err)
+			}
+		}
+		def.PortMappings = append(def.PortMappings, pm)
+	}
+
+	// ECS sizes memory in MiB and CPU in units of 1/1024 vCPU
+	def.Memory = s.Resources.Memory >> 20
+	def.MemoryReservation = s.Resources.MemoryReservation >> 20
+	def.CPU = s.Resources.NanoCPUs * 1024 / 1e9
+	return def, nil
+}
+
+// Marshal encodes defs as a containerDefinitions array.
+func Marshal(defs ...*ContainerDefinition) ([]byte, error) {
+	data, err := json.MarshalIndent(defs, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,135 @@
+// Package envdoc defines the versioned JSON document written by
+// `dockerenv export --format json`. The document is described by the JSON
+// Schema in schema/export.v1.schema.json, which is also what Validate uses.
+package envdoc
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/jsonschema"
+)
+
+// SchemaVersion is bumped on incompatible changes to Document.
+const SchemaVersion = "1"
+
+// Schema is the JSON Schema for the current SchemaVersion.
+//
+//go:embed schema/export.v1.schema.json
+var Schema []byte
+
+// Document is the top level of an export.
+type Document struct {
+	SchemaVersion string    `json:"schemaVersion"`
+	Generator     string    `json:"generator"`
+	GeneratedAt   time.Time `json:"generatedAt"`
+	Hosts         []Host    `json:"hosts"`
+}
+
+// Host groups the containers of one Docker daemon.
+type Host struct {
+	Address    string      `json:"address"`
+	Containers []Container `json:"containers"`
+}
+
+// Container is one exported container.
+type Container struct {
+	ID       string             `json:"id"`
+	Name     string             `json:"name"`
+	Image    string             `json:"image"`
+	State    string             `json:"state"`
+	Path     string             `json:"path,omitempty"`
+	Labels   map[string]string  `json:"labels"`
+	Env      []inspector.EnvVar `json:"env"`
+	Snapshot *Snapshot          `json:"snapshot,omitempty"`
+}
+
+// Snapshot holds the parameters written by export --snapshot.
+type Snapshot struct {
+	ImageID       string   `json:"imageId"`
+	Created       string   `json:"created"`
+	Entrypoint    []string `json:"entrypoint,omitempty"`
+	Cmd           []string `json:"cmd,omitempty"`
+	WorkingDir    string   `json:"workingDir,omitempty"`
+	User          string   `json:"user,omitempty"`
+	RestartPolicy string   `json:"restartPolicy,omitempty"`
+}
+
+// New returns an empty document stamped with the current time.
+func New() *Document {
+	return &Document{
+		SchemaVersion: SchemaVersion,
+		Generator:     "dockerenv",
+		GeneratedAt:   time.Now().UTC(),
+		Hosts:         []Host{},
+	}
+}
+
+// Add appends a container to the entry for its host, creating it if needed.
+func (d *Document) Add(ctr inspector.Container, path string, snapshot *Snapshot) {
+	c := Container{
+		ID:       ctr.ID,
+		Name:     ctr.Name,
+		Image:    ctr.Image,
+		State:    ctr.State,
+		Path:     path,
+		Labels:   ctr.Labels,
+		Env:      ctr.Env,
+		Snapshot: snapshot,
+	}
+	if c.Labels == nil {
+		c.Labels = map[string]string{}
+	}
+	if c.Env == nil {
+		c.Env = []inspector.EnvVar{}
+	}
+	for i := range d.Hosts {
+		if d.Hosts[i].Address == ctr.Host {
+			d.Hosts[i].Containers = append(d.Hosts[i].Containers, c)
+			return
+		}
+	}
+	d.Hosts = append(d.Hosts, Host{Address: ctr.Host, Containers: []Container{c}})
+}
+
+// Marshal encodes the document as indented JSON.
+func (d *Document) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Validate checks raw JSON against the schema for SchemaVersion.
+func Validate(data []byte) error {
+	var header struct {
+		SchemaVersion string `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if header.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("unsupported schemaVersion %q (expected %q)", header.SchemaVersion, SchemaVersion)
+	}
+	schema, err := jsonschema.Parse(Schema)
+	if err != nil {
+		return err
+	}
+	return schema.Validate(data)
+}
+
+// Parse validates and decodes a document.
+func Parse(data []byte) (*Document, error) {
+	if err := Validate(data); err != nil {
+		return nil, err
+	}
+	var d Document
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	return &d, nil
+}
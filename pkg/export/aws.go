@@ -0,0 +1,102 @@
+package export
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// newAWSSession returns a session for the region in the ?region= option,
+// falling back to the variables in regionEnv, then AWS_REGION and
+// AWS_DEFAULT_REGION. ?endpoint= points it at a local stand-in for the
+// service, and ?path_style=true addresses S3 buckets in the path as MinIO
+// and most stand-ins need.
+func newAWSSession(q url.Values, regionEnv ...string) (*session.Session, error) {
+	region := q.Get("region")
+	for _, name := range append(regionEnv, "AWS_REGION", "AWS_DEFAULT_REGION") {
+		if region != "" {
+			break
+		}
+		region = os.Getenv(name)
+	}
+	cfg := &aws.Config{Region: aws.String(region)}
+	if endpoint := q.Get("endpoint"); endpoint != "" {
+		cfg.Endpoint = aws.String(endpoint)
+	}
+	if q.Get("path_style") == "true" {
+		cfg.S3ForcePathStyle = aws.Bool(true)
+	}
+	return session.NewSession(cfg)
+}
+
+// throttleRetries is how often withBackoff retries a throttled call.
+const throttleRetries = 8
+
+// withBackoff calls f until it isn't throttled, doubling the wait from
+// 100ms up to 5s between tries. codes are throttling errors the SDK
+// doesn't recognise itself.
+func withBackoff(f func() error, codes ...string) error {
+	wait := 100 * time.Millisecond
+	for i := 0; ; i++ {
+		err := f()
+		if err == nil || i == throttleRetries || !isThrottle(err, codes) {
+			return err
+		}
+		Log.Debugf("throttled, retrying in %s: %s", wait, err)
+		time.Sleep(wait)
+		if wait *= 2; wait > 5*time.Second {
+			wait = 5 * time.Second
+		}
+	}
+}
+
+func isThrottle(err error, codes []string) bool {
+	if request.IsErrorThrottle(err) {
+		return true
+	}
+	if aerr, ok := err.(awserr.Error); ok {
+		for _, code := range codes {
+			if aerr.Code() == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxTags is the most tags SSM parameters and secrets can have.
+const maxTags = 50
+
+var tagChars = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// labelTags returns the labels that can be AWS tags as sorted key/value
+// pairs, up to max, logging the ones left out of resource.
+func labelTags(resource string, labels map[string]string, max int) [][2]string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tags [][2]string
+	for _, k := range keys {
+		v := labels[k]
+		if k == "" || len(k) > 128 || len(v) > 256 || !tagChars.MatchString(k) || !tagChars.MatchString(v) {
+			Log.Debugf("%s: label %s can't be a tag", resource, k)
+			continue
+		}
+		if len(tags) == max {
+			Log.Warningf("%s: only the first %d labels are added as tags", resource, max)
+			break
+		}
+		tags = append(tags, [2]string{k, v})
+	}
+	return tags
+}
@@ -0,0 +1,130 @@
+package export
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// setenv sets the variables in env for the length of the test, unsetting
+// those given as empty.
+func setenv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for name, value := range env {
+		old, had := os.LookupEnv(name)
+		if value == "" {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, value)
+		}
+		name := name
+		t.Cleanup(func() {
+			if had {
+				os.Setenv(name, old)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+}
+
+func TestAWSSessionRegion(t *testing.T) {
+	tests := []struct {
+		query     string
+		regionEnv []string
+		env       map[string]string
+		want      string
+	}{
+		{"region=eu-west-1", []string{"S3_REGION"}, map[string]string{"S3_REGION": "us-east-2", "AWS_REGION": "us-west-1"}, "eu-west-1"},
+		{"", []string{"S3_REGION"}, map[string]string{"S3_REGION": "us-east-2", "AWS_REGION": "us-west-1"}, "us-east-2"},
+		{"", nil, map[string]string{"S3_REGION": "us-east-2", "AWS_REGION": "us-west-1"}, "us-west-1"},
+		{"", []string{"S3_REGION"}, map[string]string{"S3_REGION": "", "AWS_REGION": "", "AWS_DEFAULT_REGION": "ap-south-1"}, "ap-south-1"},
+	}
+	for _, tt := range tests {
+		setenv(t, map[string]string{"S3_REGION": "", "AWS_REGION": "", "AWS_DEFAULT_REGION": ""})
+		setenv(t, tt.env)
+		q, _ := url.ParseQuery(tt.query)
+		sess, err := newAWSSession(q, tt.regionEnv...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := aws.StringValue(sess.Config.Region); got != tt.want {
+			t.Errorf("newAWSSession(%q, %v) with %v: region %q, want %q", tt.query, tt.regionEnv, tt.env, got, tt.want)
+		}
+	}
+}
+
+// dummyCredentials points the SDK at made-up credentials and a region for
+// the length of the test.
+func dummyCredentials(t *testing.T) {
+	t.Helper()
+	setenv(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDTEST",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "",
+		"AWS_REGION":            "us-east-1",
+		"S3_REGION":             "",
+	})
+}
+
+// testOptions is a map-backed Options.
+type testOptions map[string]interface{}
+
+func (o testOptions) String(name string) string {
+	s, _ := o[name].(string)
+	return s
+}
+
+func (o testOptions) StringSlice(name string) []string {
+	s, _ := o[name].([]string)
+	return s
+}
+
+func (o testOptions) Bool(name string) bool {
+	b, _ := o[name].(bool)
+	return b
+}
+
+func (o testOptions) Int(name string) int {
+	i, _ := o[name].(int)
+	return i
+}
+
+// awsError is returned by a fake handler to fail a call with an AWS error
+// code.
+type awsError string
+
+// fakeJSONService serves an AWS JSON-protocol API such as SSM or Secrets
+// Manager. handle gets the operation from X-Amz-Target, e.g.
+// "PutParameter", and the request body, and returns the response body or
+// an awsError.
+func fakeJSONService(t *testing.T, handle func(op string, body []byte) interface{}) *httptest.Server {
+	t.Helper()
+	dummyCredentials(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		target := r.Header.Get("X-Amz-Target")
+		out := handle(target[strings.LastIndex(target, ".")+1:], body)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if code, ok := out.(awsError); ok {
+			w.WriteHeader(http.StatusBadRequest)
+			out = map[string]string{"__type": string(code), "message": string(code)}
+		}
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
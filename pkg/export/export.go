@@ -0,0 +1,139 @@
+// Package export separates what an export produces from where it goes.
+//
+// A Formatter turns a Batch of inspected containers into Items: files
+// such as a container's env file, or key/value pairs such as one variable.
+// A Sink stores Items: in a directory, on stdout, in S3 or in SSM. Both
+// are looked up by name in a registry, so any format can be written to
+// any sink.
+package export
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/cmattoon/dockerenv/pkg/envdoc"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/layout"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+	"github.com/cmattoon/dockerenv/pkg/spec"
+)
+
+// Log receives the warnings of formatters and the progress of sinks.
+var Log logrus.FieldLogger = logrus.New()
+
+// Container is an inspected container and where it is exported to.
+type Container struct {
+	inspector.Container
+	// Path is the container's place in the export, from the layout.
+	Path string
+	// Raw and Spec are set for formats that need them (see Format).
+	Raw  *types.ContainerJSON
+	Spec *spec.Spec
+	// Snapshot is set with --snapshot.
+	Snapshot *envdoc.Snapshot
+}
+
+// Batch is everything a Formatter works from.
+type Batch struct {
+	Containers []*Container
+	Index      layout.Index
+	Classifier *secrets.Classifier
+}
+
+// Item is one unit of output. Files and key/value pairs are both items;
+// sinks decide how to store them.
+type Item struct {
+	// Key is the item's path relative to the sink: a file name, an object
+	// key or a parameter name.
+	Key         string
+	Data        []byte
+	ContentType string
+	// Secret marks items holding secret values, which file sinks write
+	// readable by the owner only. Items with every value of a container
+	// are secret, since the classifier can miss some.
+	Secret bool
+	// Container is the container the item was made from; nil for items
+	// about the whole export such as indexes.
+	Container *Container
+	// Variable is the variable name of key/value items.
+	Variable string
+	// Meta marks the metadata item written next to a container's
+	// key/value items (see MetaKey).
+	Meta bool
+}
+
+// Options gives formatters and sinks access to their settings by flag
+// name. *cli.Context satisfies it.
+type Options interface {
+	String(name string) string
+	StringSlice(name string) []string
+	Bool(name string) bool
+	Int(name string) int
+}
+
+// Formatter renders a batch.
+type Formatter interface {
+	Format(b *Batch) ([]Item, error)
+}
+
+// Sink stores items. Close flushes anything buffered and must be called
+// once all items are written.
+type Sink interface {
+	Put(item Item) error
+	Close() error
+}
+
+// Format describes a registered formatter.
+type Format struct {
+	Name  string
+	Usage string
+	// PerContainer formats write files under each container's path and get
+	// an index.yaml next to them.
+	PerContainer bool
+	// Snapshots formats include Container.Snapshot in their output.
+	// PerContainer formats get it as a container-meta.yaml item instead.
+	Snapshots bool
+	// NeedsSpec formats read Container.Raw and Container.Spec.
+	NeedsSpec func(opts Options) bool
+	New       func(opts Options) (Formatter, error)
+}
+
+var formats = map[string]*Format{}
+
+// RegisterFormat adds f to the registry, replacing any format with the
+// same name.
+func RegisterFormat(f *Format) {
+	formats[f.Name] = f
+}
+
+// LookupFormat returns the registered format called name.
+func LookupFormat(name string) (*Format, error) {
+	if f, ok := formats[name]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unknown format '%s' (valid: %v)", name, FormatNames())
+}
+
+// FormatNames returns the names of the registered formats, sorted.
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// needsSpec is the NeedsSpec of formats that always need a spec.
+func needsSpec(Options) bool { return true }
+
+// FormatterFunc adapts a function to the Formatter interface.
+type FormatterFunc func(b *Batch) ([]Item, error)
+
+// Format implements Formatter.
+func (f FormatterFunc) Format(b *Batch) ([]Item, error) {
+	return f(b)
+}
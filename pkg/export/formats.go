@@ -0,0 +1,417 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cmattoon/dockerenv/pkg/dotenv"
+	"github.com/cmattoon/dockerenv/pkg/ecs"
+	"github.com/cmattoon/dockerenv/pkg/envdoc"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/k8s"
+	"github.com/cmattoon/dockerenv/pkg/layout"
+	"github.com/cmattoon/dockerenv/pkg/nomad"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+	"github.com/cmattoon/dockerenv/pkg/systemd"
+	"github.com/cmattoon/dockerenv/pkg/table"
+	"github.com/cmattoon/dockerenv/pkg/varfile"
+)
+
+// ContainersDir is the directory per-container files are written under.
+const ContainersDir = "containers"
+
+func init() {
+	RegisterFormat(&Format{Name: "env", Usage: "an env file per container", PerContainer: true, New: newEnv})
+	RegisterFormat(&Format{Name: "json", Usage: "one versioned JSON document", Snapshots: true, New: newJSON})
+	RegisterFormat(&Format{Name: "yaml", Usage: "one YAML map of every container's variables", New: newYAML})
+	RegisterFormat(&Format{Name: "csv", Usage: "one RFC 4180 row per variable", New: newTable("csv")})
+	RegisterFormat(&Format{Name: "tsv", Usage: "one tab-separated row per variable", New: newTable("tsv")})
+	RegisterFormat(&Format{Name: "kv", Usage: "one key/value item per variable at PATH/NAME, and metadata at PATH/.dockerenv", Snapshots: true, New: newKV})
+	RegisterFormat(&Format{Name: "k8s", Usage: "a ConfigMap, Secret and envFrom snippet per container", PerContainer: true, New: newK8s})
+	RegisterFormat(&Format{Name: "systemd", Usage: "an EnvironmentFile and optional unit per container", PerContainer: true, New: newSystemd,
+		NeedsSpec: func(opts Options) bool { return opts.String("systemd-unit") != "none" }})
+	RegisterFormat(&Format{Name: "tfvars", Usage: "terraform.tfvars and variables.tf per container", PerContainer: true, New: newVarfile("tfvars")})
+	RegisterFormat(&Format{Name: "helm", Usage: "a Helm values.yaml per container", PerContainer: true, New: newVarfile("helm")})
+	RegisterFormat(&Format{Name: "ecs", Usage: "an ECS containerDefinitions fragment per container", PerContainer: true, NeedsSpec: needsSpec, New: newECS})
+	RegisterFormat(&Format{Name: "nomad", Usage: "a Nomad task stanza per container", PerContainer: true, NeedsSpec: needsSpec, New: newNomad})
+}
+
+// containerKey returns the key of a per-container file.
+func containerKey(c *Container, name string) string {
+	return path.Join(ContainersDir, c.Path, name)
+}
+
+// IndexItem returns the index.yaml written next to per-container files.
+func IndexItem(b *Batch) (Item, error) {
+	data, err := yaml.Marshal(b.Index)
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return Item{Key: path.Join(ContainersDir, "index.yaml"), Data: data, ContentType: "application/yaml"}, nil
+}
+
+func newEnv(opts Options) (Formatter, error) {
+	dialect, err := dotenv.ParseDialect(opts.String("env-dialect"))
+	if err != nil {
+		return nil, err
+	}
+	return FormatterFunc(func(b *Batch) ([]Item, error) {
+		var items []Item
+		for _, c := range b.Containers {
+			data, err := dotenv.Marshal(c.Env, dialect)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s as a %s env file: %w", c.Name, dialect, err)
+			}
+			items = append(items, Item{Key: containerKey(c, "container.env"), Data: data, ContentType: "text/plain", Secret: true, Container: c})
+		}
+		return items, nil
+	}), nil
+}
+
+func newJSON(opts Options) (Formatter, error) {
+	return FormatterFunc(func(b *Batch) ([]Item, error) {
+		doc := envdoc.New()
+		for _, c := range b.Containers {
+			doc.Add(c.Container, c.Path, c.Snapshot)
+		}
+		data, err := doc.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		if err := envdoc.Validate(data); err != nil {
+			return nil, fmt.Errorf("generated document is invalid: %w", err)
+		}
+		return []Item{{Key: "dockerenv.json", Data: data, ContentType: "application/json", Secret: true}}, nil
+	}), nil
+}
+
+func newYAML(opts Options) (Formatter, error) {
+	return FormatterFunc(func(b *Batch) ([]Item, error) {
+		values := map[string]map[string]string{}
+		for _, c := range b.Containers {
+			values[c.ShortID()] = envMap(c)
+		}
+		data, err := yaml.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+		return []Item{{Key: "dockerenv.yaml", Data: data, ContentType: "application/yaml", Secret: true}}, nil
+	}), nil
+}
+
+// envMap returns the variables of c by name.
+func envMap(c *Container) map[string]string {
+	values := map[string]string{}
+	for _, ev := range c.Env {
+		values[ev.Name] = ev.Value
+	}
+	return values
+}
+
+func newTable(format string) func(opts Options) (Formatter, error) {
+	return func(opts Options) (Formatter, error) {
+		policy, err := secrets.ParsePolicy(opts.String("value-policy"))
+		if err != nil {
+			return nil, err
+		}
+		rfc4180 := opts.Bool("rfc4180")
+		return FormatterFunc(func(b *Batch) ([]Item, error) {
+			ctrs := make([]inspector.Container, len(b.Containers))
+			for i, c := range b.Containers {
+				ctrs[i] = c.Container
+			}
+			rows := table.Rows(ctrs, b.Classifier, policy)
+			if !rfc4180 {
+				rows = table.Defuse(rows)
+			}
+			var buf bytes.Buffer
+			var err error
+			contentType := "text/csv"
+			if format == "tsv" {
+				err = table.WriteTSV(&buf, rows)
+				contentType = "text/tab-separated-values"
+			} else {
+				err = table.WriteCSV(&buf, rows)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return []Item{{Key: "dockerenv." + format, Data: buf.Bytes(), ContentType: contentType, Secret: true}}, nil
+		}), nil
+	}
+}
+
+func newKV(opts Options) (Formatter, error) {
+	return FormatterFunc(func(b *Batch) ([]Item, error) {
+		var items []Item
+		for _, c := range b.Containers {
+			for _, ev := range c.Env {
+				items = append(items, Item{
+					Key:         KVKey(c.Path, ev.Name),
+					Data:        []byte(ev.Value),
+					ContentType: "text/plain",
+					Secret:      b.Classifier.IsSecret(ev.Name, ev.Value),
+					Container:   c,
+					Variable:    ev.Name,
+				})
+			}
+			meta, err := kvMeta(c)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, Item{
+				Key:         KVKey(c.Path, MetaKey),
+				Data:        meta,
+				ContentType: "application/json",
+				Container:   c,
+				Meta:        true,
+			})
+		}
+		return items, nil
+	}), nil
+}
+
+// MetaKey is the name of the item the kv format writes next to each
+// container's variables. It holds an export document (see envdoc) for
+// that container alone, with the variables' names and sources in order
+// but not their values, so the container can be imported again.
+const MetaKey = ".dockerenv"
+
+// kvMeta returns the metadata item of c. Labels are left out to fit the
+// 4KB limit of an SSM parameter.
+func kvMeta(c *Container) ([]byte, error) {
+	ctr := c.Container
+	ctr.Labels = nil
+	ctr.Env = make([]inspector.EnvVar, len(c.Env))
+	for i, ev := range c.Env {
+		ctr.Env[i] = inspector.EnvVar{Name: ev.Name, Source: ev.Source}
+	}
+	doc := envdoc.New()
+	doc.Add(ctr, c.Path, c.Snapshot)
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return data, nil
+}
+
+// KVKey returns the key of a variable in the kv format.
+func KVKey(ctrPath, name string) string {
+	return path.Join(ctrPath, name)
+}
+
+func newK8s(opts Options) (Formatter, error) {
+	names, err := layout.Parse(opts.String("k8s-name"))
+	if err != nil {
+		return nil, err
+	}
+	labels, err := ParseKeyValues(opts.StringSlice("k8s-label"))
+	if err != nil {
+		return nil, err
+	}
+	namespace := opts.String("namespace")
+	return FormatterFunc(func(b *Batch) ([]Item, error) {
+		var items []Item
+		for _, c := range b.Containers {
+			name, err := names.Path(c.Container)
+			if err != nil {
+				return nil, err
+			}
+			kopts := k8s.DefaultOptions(c.Container, name)
+			if namespace != "" {
+				kopts.Namespace = namespace
+			}
+			for k, v := range labels {
+				kopts.Labels[k] = v
+			}
+			res, err := k8s.Generate(c.Container, b.Classifier, kopts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate manifests for %s: %w", c.Name, err)
+			}
+			for _, skipped := range res.Skipped {
+				Log.Warningf("%s: skipping %s, which is not a valid ConfigMap key", c.Name, skipped)
+			}
+			items = append(items,
+				Item{Key: containerKey(c, "k8s.yaml"), Data: res.Manifests, ContentType: "application/yaml", Secret: true, Container: c},
+				Item{Key: containerKey(c, "envfrom.yaml"), Data: res.EnvFrom, ContentType: "application/yaml", Container: c},
+			)
+		}
+		return items, nil
+	}), nil
+}
+
+func newSystemd(opts Options) (Formatter, error) {
+	unit := opts.String("systemd-unit")
+	if unit != "none" && unit != "service" && unit != "podman" {
+		return nil, fmt.Errorf("unknown --systemd-unit '%s' (valid: none, service, podman)", unit)
+	}
+	inline := opts.Bool("systemd-inline") && unit != "none"
+	envDir := opts.String("systemd-env-dir")
+	return FormatterFunc(func(b *Batch) ([]Item, error) {
+		var items []Item
+		for _, c := range b.Containers {
+			name := systemd.UnitName(c.Name)
+			if unit == "podman" {
+				name = "container-" + name
+			}
+			vars, skipped := systemd.Split(c.Env)
+			for _, skip := range skipped {
+				Log.Warningf("%s: skipping %s, which is not a valid systemd variable name", c.Name, skip)
+			}
+
+			sopts := systemd.Options{}
+			if !inline {
+				data, err := systemd.EnvironmentFile(vars)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, Item{Key: containerKey(c, name+".env"), Data: data, ContentType: "text/plain", Secret: true, Container: c})
+				sopts.EnvironmentFile = path.Join(envDir, name+".env")
+			}
+
+			var data []byte
+			switch unit {
+			case "service":
+				var argv []string
+				if c.Raw != nil && c.Raw.Config != nil {
+					argv = append(append(argv, c.Raw.Config.Entrypoint...), c.Raw.Config.Cmd...)
+				}
+				data = systemd.ServiceUnit(*c.Spec, argv, vars, sopts)
+			case "podman":
+				data = systemd.PodmanUnit(*c.Spec, vars, sopts)
+			default:
+				continue
+			}
+			items = append(items, Item{Key: containerKey(c, name+".service"), Data: data, ContentType: "text/plain", Secret: inline, Container: c})
+		}
+		return items, nil
+	}), nil
+}
+
+func newVarfile(format string) func(opts Options) (Formatter, error) {
+	return func(opts Options) (Formatter, error) {
+		name := opts.String("key-convention")
+		if name == "" {
+			name = map[string]string{"tfvars": "snake", "helm": "camel"}[format]
+		}
+		conv, err := varfile.ParseConvention(name)
+		if err != nil {
+			return nil, err
+		}
+		split := opts.String("sensitive")
+		if split != "split" && split != "inline" {
+			return nil, fmt.Errorf("unknown --sensitive '%s' (valid: split, inline)", split)
+		}
+		root := opts.String("helm-root")
+
+		return FormatterFunc(func(b *Batch) ([]Item, error) {
+			var items []Item
+			add := func(c *Container, name string, data []byte, secret bool, err error) error {
+				if err != nil {
+					return err
+				}
+				contentType := "text/plain"
+				if strings.HasSuffix(name, ".yaml") {
+					contentType = "application/yaml"
+				}
+				items = append(items, Item{Key: containerKey(c, name), Data: data, ContentType: contentType, Secret: secret, Container: c})
+				return nil
+			}
+			for _, c := range b.Containers {
+				entries, skipped := varfile.Build(c.Env, b.Classifier, conv)
+				for _, skip := range skipped {
+					Log.Warningf("%s: skipping %s, whose key is empty or already used", c.Name, skip)
+				}
+				plain, sensitive := entries, []varfile.Entry(nil)
+				if split == "split" {
+					plain, sensitive = varfile.Split(entries)
+				}
+				inlineSecrets := split == "inline"
+
+				if format == "tfvars" {
+					data, err := varfile.TFVars(plain)
+					if err := add(c, "terraform.tfvars", data, inlineSecrets, err); err != nil {
+						return nil, err
+					}
+					data, err = varfile.TFVariables(entries)
+					if err := add(c, "variables.tf", data, false, err); err != nil {
+						return nil, err
+					}
+					if len(sensitive) > 0 {
+						data, err := varfile.TFVars(sensitive)
+						if err := add(c, "secrets.tfvars", data, true, err); err != nil {
+							return nil, err
+						}
+					}
+					continue
+				}
+				data, err := varfile.HelmValues(plain, root)
+				if err := add(c, "values.yaml", data, inlineSecrets, err); err != nil {
+					return nil, err
+				}
+				if len(sensitive) > 0 {
+					data, err := varfile.HelmValues(sensitive, root)
+					if err := add(c, "secrets.yaml", data, true, err); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return items, nil
+		}), nil
+	}
+}
+
+func newECS(opts Options) (Formatter, error) {
+	pathPrefix := opts.String("path-prefix")
+	return FormatterFunc(func(b *Batch) ([]Item, error) {
+		var items []Item
+		for _, c := range b.Containers {
+			ctrPath := c.Path
+			def, err := ecs.Generate(*c.Spec, c.Env, ecs.Options{
+				Classifier: b.Classifier,
+				SecretPath: func(name string) string { return path.Join("/", pathPrefix, KVKey(ctrPath, name)) },
+			})
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", c.Name, err)
+			}
+			data, err := ecs.Marshal(def)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, Item{Key: containerKey(c, "ecs-container.json"), Data: data, ContentType: "application/json", Container: c})
+		}
+		return items, nil
+	}), nil
+}
+
+func newNomad(opts Options) (Formatter, error) {
+	return FormatterFunc(func(b *Batch) ([]Item, error) {
+		var items []Item
+		for _, c := range b.Containers {
+			data, err := nomad.Task(*c.Spec, c.Env)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", c.Name, err)
+			}
+			items = append(items, Item{Key: containerKey(c, "nomad-task.hcl"), Data: data, ContentType: "text/plain", Secret: true, Container: c})
+		}
+		return items, nil
+	}), nil
+}
+
+// ParseKeyValues parses repeated key=value flags.
+func ParseKeyValues(kvs []string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, kv := range kvs {
+		x := strings.SplitN(kv, "=", 2)
+		if len(x) != 2 || x[0] == "" {
+			return nil, fmt.Errorf("invalid '%s': expected key=value", kv)
+		}
+		m[x[0]] = x[1]
+	}
+	return m, nil
+}
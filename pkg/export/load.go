@@ -0,0 +1,273 @@
+package export
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/ssm"
+
+	"github.com/cmattoon/dockerenv/pkg/envdoc"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+// Loader reads one container back from what a sink wrote. It reads
+// either an export document (--format json) or a directory of key/value
+// items with their metadata (--format kv). Options holds the
+// "container-id" used to pick a container from a document.
+type Loader func(u *url.URL, opts Options) (*envdoc.Container, error)
+
+var loaders = map[string]Loader{}
+
+func init() {
+	RegisterLoader("file", loadFile)
+	RegisterLoader("s3", loadS3)
+	RegisterLoader("ssm", loadSSM)
+}
+
+// RegisterLoader makes l the loader for URIs with the given scheme.
+func RegisterLoader(scheme string, l Loader) {
+	loaders[scheme] = l
+}
+
+// Load reads the container at source, which is a URI such as
+// ssm:///prod/api or s3://bucket/envs/dockerenv.json, or a local path.
+func Load(source string, opts Options) (*envdoc.Container, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source '%s': %w", source, err)
+	}
+	if u.Scheme == "" {
+		u = &url.URL{Scheme: "file", Path: source}
+	}
+	l, ok := loaders[u.Scheme]
+	if !ok {
+		schemes := make([]string, 0, len(loaders))
+		for scheme := range loaders {
+			schemes = append(schemes, scheme)
+		}
+		sort.Strings(schemes)
+		return nil, fmt.Errorf("can't import from '%s' (valid schemes: %v)", source, schemes)
+	}
+	return l(u, opts)
+}
+
+// fromDocument picks the container matching selector (an ID prefix, a
+// name or a path) from an export document. Without a selector the
+// document must hold a single container.
+func fromDocument(data []byte, selector string) (*envdoc.Container, error) {
+	doc, err := envdoc.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	var found []*envdoc.Container
+	for i := range doc.Hosts {
+		for j := range doc.Hosts[i].Containers {
+			c := &doc.Hosts[i].Containers[j]
+			if selector == "" || strings.HasPrefix(c.ID, selector) || strings.TrimPrefix(c.Name, "/") == strings.TrimPrefix(selector, "/") || c.Path == selector {
+				found = append(found, c)
+			}
+		}
+	}
+	switch {
+	case len(found) == 1:
+		Log.Infof("Importing %s from an export made on %s", found[0].Name, doc.GeneratedAt.Format("2006-01-02 15:04:05"))
+		return found[0], nil
+	case len(found) == 0:
+		return nil, fmt.Errorf("no container matches '%s'", selector)
+	case selector == "":
+		return nil, fmt.Errorf("the export holds %d containers; pick one with --container-id", len(found))
+	}
+	return nil, fmt.Errorf("%d containers match '%s'", len(found), selector)
+}
+
+// fromKV rebuilds a container from its metadata item and the values of
+// its variables. where is the location the items were read from, which
+// must end with the path recorded in the metadata.
+func fromKV(where string, meta []byte, values map[string]string) (*envdoc.Container, error) {
+	if meta == nil {
+		return nil, fmt.Errorf("%s has no %s metadata; is it a --format kv export of one container?", where, MetaKey)
+	}
+	c, err := fromDocument(meta, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid metadata: %w", path.Join(where, MetaKey), err)
+	}
+	where = strings.TrimSuffix(where, "/")
+	if c.Path != "" && where != c.Path && !strings.HasSuffix(where, "/"+c.Path) {
+		return nil, fmt.Errorf("%s holds the metadata of %s, which was exported to %s", where, c.Name, c.Path)
+	}
+
+	for i, ev := range c.Env {
+		v, ok := values[ev.Name]
+		if !ok {
+			// SSM can't store empty values.
+			Log.Warningf("%s: %s was not found; importing it as empty", where, ev.Name)
+		}
+		c.Env[i].Value = v
+		delete(values, ev.Name)
+	}
+	extra := make([]string, 0, len(values))
+	for name := range values {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		Log.Warningf("%s: %s was added after the export", where, name)
+		c.Env = append(c.Env, inspector.EnvVar{Name: name, Value: values[name], Source: inspector.SourceContainer})
+	}
+	return c, nil
+}
+
+// loadFile reads a document file, or a directory written by --format kv.
+func loadFile(u *url.URL, opts Options) (*envdoc.Container, error) {
+	if _, err := queryOptions(u); err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		data, err := ioutil.ReadFile(u.Path)
+		if err != nil {
+			return nil, err
+		}
+		return fromDocument(data, opts.String("container-id"))
+	}
+
+	entries, err := ioutil.ReadDir(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	var meta []byte
+	values := map[string]string{}
+	for _, e := range entries {
+		if !e.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(u.Path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if e.Name() == MetaKey {
+			meta = data
+			continue
+		}
+		values[e.Name()] = string(data)
+	}
+	return fromKV(filepath.ToSlash(filepath.Clean(u.Path)), meta, values)
+}
+
+// loadS3 reads a document object, or the objects under a prefix ending
+// in "/" written by --format kv.
+//
+// Options: region, endpoint and path_style.
+func loadS3(u *url.URL, opts Options) (*envdoc.Container, error) {
+	q, err := queryOptions(u, "region", "endpoint", "path_style")
+	if err != nil {
+		return nil, err
+	}
+	sess, err := newAWSSession(q, "S3_REGION")
+	if err != nil {
+		return nil, err
+	}
+	client := s3.New(sess)
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	get := func(key string) ([]byte, error) {
+		out, err := client.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		if err != nil {
+			return nil, fmt.Errorf("s3://%s/%s: %w", bucket, key, err)
+		}
+		defer out.Body.Close()
+		return ioutil.ReadAll(out.Body)
+	}
+	if key != "" && !strings.HasSuffix(key, "/") {
+		data, err := get(key)
+		if err != nil {
+			return nil, err
+		}
+		return fromDocument(data, opts.String("container-id"))
+	}
+
+	var meta []byte
+	values := map[string]string{}
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(key), Delimiter: aws.String("/")}
+	var getErr error
+	err = client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, obj := range page.Contents {
+			var data []byte
+			if data, getErr = get(aws.StringValue(obj.Key)); getErr != nil {
+				return false
+			}
+			name := path.Base(aws.StringValue(obj.Key))
+			if name == MetaKey {
+				meta = data
+			} else {
+				values[name] = string(data)
+			}
+		}
+		return true
+	})
+	if getErr != nil {
+		return nil, getErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromKV(key, meta, values)
+}
+
+// loadSSM reads the parameters directly under a path written by
+// --format kv.
+//
+// Options: region and endpoint.
+func loadSSM(u *url.URL, opts Options) (*envdoc.Container, error) {
+	q, err := queryOptions(u, "region", "endpoint")
+	if err != nil {
+		return nil, err
+	}
+	sess, err := newAWSSession(q)
+	if err != nil {
+		return nil, err
+	}
+	client := ssm.New(sess)
+	prefix := path.Join("/", u.Host, u.Path)
+
+	var meta []byte
+	values := map[string]string{}
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(prefix),
+		WithDecryption: aws.Bool(true),
+	}
+	for {
+		var out *ssm.GetParametersByPathOutput
+		err := withBackoff(func() error {
+			var err error
+			out, err = client.GetParametersByPath(input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", prefix, err)
+		}
+		for _, p := range out.Parameters {
+			name := path.Base(aws.StringValue(p.Name))
+			if name == MetaKey {
+				meta = []byte(aws.StringValue(p.Value))
+			} else {
+				values[name] = aws.StringValue(p.Value)
+			}
+		}
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	return fromKV(prefix, meta, values)
+}
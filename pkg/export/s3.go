@@ -0,0 +1,171 @@
+package export
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/cmattoon/dockerenv/pkg/envdoc"
+)
+
+func init() {
+	RegisterSink("s3", newS3Sink)
+}
+
+// ManifestKey is the object the s3 sink lists everything it wrote in.
+const ManifestKey = "manifest.json"
+
+// s3MaxTags is the most tags an object can have.
+const s3MaxTags = 10
+
+// s3Sink puts each item as an object under a key prefix, tagged with the
+// labels of its container, and writes a manifest of the objects on
+// Close. Uploads are checked with Content-MD5, and the ETag S3 returns is
+// compared with the MD5 unless SSE-KMS makes it opaque.
+//
+// The region falls back to S3_REGION before the usual AWS variables.
+//
+// Options: region, endpoint, path_style, sse (AES256 or s3 for SSE-S3,
+// aws:kms or kms for SSE-KMS) and kms_key.
+type s3Sink struct {
+	bucket   string
+	prefix   string
+	sse      string
+	kmsKey   string
+	client   *s3.S3
+	manifest Manifest
+}
+
+// Manifest describes the objects of one export.
+type Manifest struct {
+	SchemaVersion string           `json:"schemaVersion"`
+	Generator     string           `json:"generator"`
+	GeneratedAt   time.Time        `json:"generatedAt"`
+	Objects       []ManifestObject `json:"objects"`
+}
+
+// ManifestObject is one object in a Manifest.
+type ManifestObject struct {
+	Key         string `json:"key"`
+	Size        int    `json:"size"`
+	MD5         string `json:"md5"`
+	ContentType string `json:"contentType,omitempty"`
+	Secret      bool   `json:"secret,omitempty"`
+	Container   string `json:"container,omitempty"`
+	Path        string `json:"path,omitempty"`
+}
+
+func newS3Sink(u *url.URL, opts Options) (Sink, error) {
+	q, err := queryOptions(u, "region", "endpoint", "path_style", "sse", "kms_key")
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("s3 URIs need a bucket, such as s3://my-bucket/prefix")
+	}
+	s := &s3Sink{
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+		kmsKey: q.Get("kms_key"),
+		manifest: Manifest{
+			SchemaVersion: envdoc.SchemaVersion,
+			Generator:     "dockerenv",
+			GeneratedAt:   time.Now().UTC(),
+			Objects:       []ManifestObject{},
+		},
+	}
+	switch q.Get("sse") {
+	case "":
+	case s3.ServerSideEncryptionAes256, "s3":
+		s.sse = s3.ServerSideEncryptionAes256
+	case s3.ServerSideEncryptionAwsKms, "kms":
+		s.sse = s3.ServerSideEncryptionAwsKms
+	default:
+		return nil, fmt.Errorf("unknown sse '%s' (valid: AES256, aws:kms)", q.Get("sse"))
+	}
+	if s.kmsKey != "" && s.sse == "" {
+		s.sse = s3.ServerSideEncryptionAwsKms
+	}
+	if s.kmsKey != "" && s.sse != s3.ServerSideEncryptionAwsKms {
+		return nil, fmt.Errorf("kms_key needs sse=aws:kms")
+	}
+	sess, err := newAWSSession(q, "S3_REGION")
+	if err != nil {
+		return nil, err
+	}
+	s.client = s3.New(sess)
+	return s, nil
+}
+
+func (s *s3Sink) Put(item Item) error {
+	var tags [][2]string
+	obj := ManifestObject{Key: item.Key, ContentType: item.ContentType, Secret: item.Secret}
+	if c := item.Container; c != nil {
+		obj.Container, obj.Path = c.ID, c.Path
+		tags = labelTags(item.Key, c.Labels, s3MaxTags)
+	}
+	sum, err := s.put(item.Key, item.Data, item.ContentType, tags)
+	if err != nil {
+		return err
+	}
+	obj.Size, obj.MD5 = len(item.Data), sum
+	s.manifest.Objects = append(s.manifest.Objects, obj)
+	return nil
+}
+
+func (s *s3Sink) Close() error {
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	_, err = s.put(ManifestKey, append(data, '\n'), "application/json", nil)
+	return err
+}
+
+// put uploads one object and returns the hex MD5 of data.
+func (s *s3Sink) put(key string, data []byte, contentType string, tags [][2]string) (string, error) {
+	key = path.Join(s.prefix, key)
+	sum := md5.Sum(data)
+	Log.Infof("Writing %d bytes to s3://%s/%s", len(data), s.bucket, key)
+	input := &s3.PutObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(key),
+		Body:       bytes.NewReader(data),
+		ContentMD5: aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if s.sse != "" {
+		input.ServerSideEncryption = aws.String(s.sse)
+	}
+	if s.kmsKey != "" {
+		input.SSEKMSKeyId = aws.String(s.kmsKey)
+	}
+	if len(tags) > 0 {
+		v := url.Values{}
+		for _, kv := range tags {
+			v.Set(kv[0], kv[1])
+		}
+		input.Tagging = aws.String(v.Encode())
+	}
+	out, err := s.client.PutObject(input)
+	if err != nil {
+		return "", err
+	}
+	want := hex.EncodeToString(sum[:])
+	if etag := strings.Trim(aws.StringValue(out.ETag), `"`); s.sse != s3.ServerSideEncryptionAwsKms && etag != want {
+		return "", fmt.Errorf("s3://%s/%s: ETag %s doesn't match MD5 %s", s.bucket, key, etag, want)
+	}
+	return want, nil
+}
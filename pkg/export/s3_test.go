@@ -0,0 +1,140 @@
+package export
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+type fakeObject struct {
+	data    []byte
+	headers http.Header
+}
+
+// fakeS3 stores the objects put into one bucket, checking Content-MD5 as
+// S3 does. badETag makes it answer with a wrong ETag.
+type fakeS3 struct {
+	t       *testing.T
+	objects map[string]fakeObject
+	badETag bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut || !strings.HasPrefix(r.URL.Path, "/bucket/") {
+		f.t.Errorf("unexpected S3 call %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		f.t.Error(err)
+		return
+	}
+	sum := md5.Sum(data)
+	if got := r.Header.Get("Content-MD5"); got != base64.StdEncoding.EncodeToString(sum[:]) {
+		f.t.Errorf("%s: Content-MD5 %q doesn't match the body", r.URL.Path, got)
+	}
+	f.objects[strings.TrimPrefix(r.URL.Path, "/bucket/")] = fakeObject{data: data, headers: r.Header}
+	etag := hex.EncodeToString(sum[:])
+	if f.badETag {
+		etag = strings.Repeat("0", 32)
+	}
+	w.Header().Set("ETag", `"`+etag+`"`)
+}
+
+func newFakeS3(t *testing.T, query string) (*fakeS3, Sink) {
+	t.Helper()
+	dummyCredentials(t)
+	f := &fakeS3{t: t, objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse("s3://bucket/envs?path_style=true&endpoint=" + url.QueryEscape(srv.URL) + query)
+	sink, err := newS3Sink(u, testOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, sink
+}
+
+func TestS3PerObjectWithManifest(t *testing.T) {
+	f, sink := newFakeS3(t, "&sse=AES256")
+	ctr := &Container{Container: inspector.Container{ID: "aaa111", Name: "api", Labels: map[string]string{"team": "shop"}}, Path: "api"}
+	items := []Item{
+		{Key: "containers/api/container.env", Data: []byte("LOG_LEVEL=debug\n"), ContentType: "text/plain", Container: ctr},
+		{Key: "containers/index.yaml", Data: []byte("api: aaa111\n"), ContentType: "application/yaml"},
+	}
+	for _, item := range items {
+		if err := sink.Put(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if want := []string{"envs/containers/api/container.env", "envs/containers/index.yaml", "envs/" + ManifestKey}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("objects = %v, want %v", keys, want)
+	}
+	env := f.objects["envs/containers/api/container.env"]
+	if got := env.headers.Get("X-Amz-Server-Side-Encryption"); got != "AES256" {
+		t.Errorf("SSE header = %q, want AES256", got)
+	}
+	if got := env.headers.Get("X-Amz-Tagging"); got != "team=shop" {
+		t.Errorf("tagging header = %q, want team=shop", got)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(f.objects["envs/"+ManifestKey].data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Objects) != len(items) {
+		t.Fatalf("manifest lists %d objects, want %d", len(m.Objects), len(items))
+	}
+	for i, obj := range m.Objects {
+		sum := md5.Sum(items[i].Data)
+		if obj.Key != items[i].Key || obj.Size != len(items[i].Data) || obj.MD5 != hex.EncodeToString(sum[:]) {
+			t.Errorf("manifest object %d = %+v, want key %s, size %d, md5 %x", i, obj, items[i].Key, len(items[i].Data), sum)
+		}
+	}
+	if m.Objects[0].Container != "aaa111" || m.Objects[0].Path != "api" {
+		t.Errorf("manifest object 0 = %+v, want container aaa111 at api", m.Objects[0])
+	}
+}
+
+func TestS3ETagMismatch(t *testing.T) {
+	f, sink := newFakeS3(t, "")
+	f.badETag = true
+	err := sink.Put(Item{Key: "dockerenv.json", Data: []byte("{}"), ContentType: "application/json"})
+	if err == nil || !strings.Contains(err.Error(), "doesn't match MD5") {
+		t.Errorf("Put with a wrong ETag returned %v, want a mismatch error", err)
+	}
+}
+
+func TestS3ETagIgnoredUnderKMS(t *testing.T) {
+	f, sink := newFakeS3(t, "&sse=kms&kms_key=alias/env")
+	f.badETag = true
+	if err := sink.Put(Item{Key: "dockerenv.json", Data: []byte("{}")}); err != nil {
+		t.Errorf("Put under SSE-KMS returned %v, want the opaque ETag ignored", err)
+	}
+	obj := f.objects["envs/dockerenv.json"]
+	if got := obj.headers.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"); got != "alias/env" {
+		t.Errorf("KMS key header = %q, want alias/env", got)
+	}
+}
@@ -0,0 +1,205 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+
+	"github.com/cmattoon/dockerenv/pkg/layout"
+)
+
+func init() {
+	RegisterSink("secretsmanager", newSecretsManagerSink)
+}
+
+// currentStage is the staging label of a secret's current version.
+const currentStage = "AWSCURRENT"
+
+// secretsManagerSink stores the secret variables of each container as
+// one JSON secret named /<prefix>/<project>/<service>, from the compose
+// labels, or /<prefix>/<name> for containers not started by compose.
+// Other variables are left out. Secrets are written on Close; ones whose
+// current value already matches are left alone, so no new version is
+// made. New versions get the labels in ?stage= as well as AWSCURRENT.
+//
+// Options: region, endpoint, kms_key (used when creating a secret) and
+// stage (repeatable).
+type secretsManagerSink struct {
+	prefix  string
+	kmsKey  string
+	stages  []string
+	client  *secretsmanager.SecretsManager
+	secrets map[string]*smSecret
+}
+
+// smSecret is a secret being assembled from key/value items.
+type smSecret struct {
+	values map[string]string
+	labels map[string]string
+	from   string
+}
+
+func newSecretsManagerSink(u *url.URL, opts Options) (Sink, error) {
+	q, err := queryOptions(u, "region", "endpoint", "kms_key", "stage")
+	if err != nil {
+		return nil, err
+	}
+	sess, err := newAWSSession(q)
+	if err != nil {
+		return nil, err
+	}
+	s := &secretsManagerSink{
+		prefix:  path.Join("/", u.Host, u.Path),
+		kmsKey:  q.Get("kms_key"),
+		client:  secretsmanager.New(sess),
+		secrets: map[string]*smSecret{},
+	}
+	for _, stage := range q["stage"] {
+		if stage != currentStage {
+			s.stages = append(s.stages, stage)
+		}
+	}
+	return s, nil
+}
+
+func (s *secretsManagerSink) Put(item Item) error {
+	if item.Meta || item.Variable != "" && !item.Secret {
+		return nil
+	}
+	if item.Variable == "" || item.Container == nil {
+		return fmt.Errorf("%s: Secrets Manager stores key/value items only, use --format kv", item.Key)
+	}
+	name := s.secretName(item.Container)
+	sec := s.secrets[name]
+	if sec == nil {
+		sec = &smSecret{values: map[string]string{}, labels: item.Container.Labels, from: item.Container.Name}
+		s.secrets[name] = sec
+	}
+	if old, ok := sec.values[item.Variable]; ok && old != string(item.Data) {
+		Log.Warningf("%s: %s differs between %s and %s; keeping the value of %s",
+			name, item.Variable, sec.from, item.Container.Name, sec.from)
+		return nil
+	}
+	sec.values[item.Variable] = string(item.Data)
+	return nil
+}
+
+// secretName returns the secret c's variables are stored in. Replicas of
+// a compose service share it.
+func (s *secretsManagerSink) secretName(c *Container) string {
+	d := layout.NewData(c.Container)
+	if d.Service == "" {
+		return path.Join(s.prefix, layout.SafeName(c.Name))
+	}
+	return path.Join(s.prefix, layout.SafeName(d.Project), layout.SafeName(d.Service))
+}
+
+func (s *secretsManagerSink) Close() error {
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var created, updated, unchanged, failed int
+	for _, name := range names {
+		state, err := s.write(name, s.secrets[name])
+		if err != nil {
+			Log.Errorf("failed to write secret %s: %s", name, err)
+			failed++
+			continue
+		}
+		switch state {
+		case "created":
+			created++
+		case "updated":
+			updated++
+		default:
+			unchanged++
+		}
+	}
+
+	Log.Infof("Secrets Manager %s: %d created, %d updated, %d unchanged, %d failed",
+		s.prefix, created, updated, unchanged, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d secrets could not be written", failed, len(names))
+	}
+	return nil
+}
+
+// write creates or updates one secret, returning "created", "updated" or
+// "unchanged".
+func (s *secretsManagerSink) write(name string, sec *smSecret) (string, error) {
+	data, err := json.Marshal(sec.values)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	var tags []*secretsmanager.Tag
+	for _, kv := range labelTags(name, sec.labels, maxTags) {
+		tags = append(tags, &secretsmanager.Tag{Key: aws.String(kv[0]), Value: aws.String(kv[1])})
+	}
+
+	cur, err := s.client.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId:     aws.String(name),
+		VersionStage: aws.String(currentStage),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		input := &secretsmanager.CreateSecretInput{
+			Name:         aws.String(name),
+			Description:  aws.String("Environment of " + sec.from + ", exported by dockerenv"),
+			SecretString: aws.String(string(data)),
+			Tags:         tags,
+		}
+		if s.kmsKey != "" {
+			input.KmsKeyId = aws.String(s.kmsKey)
+		}
+		out, err := s.client.CreateSecret(input)
+		if err != nil {
+			return "", err
+		}
+		for _, stage := range s.stages {
+			_, err := s.client.UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+				SecretId:        aws.String(name),
+				VersionStage:    aws.String(stage),
+				MoveToVersionId: out.VersionId,
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to add stage %s: %w", stage, err)
+			}
+		}
+		Log.Infof("Created secret %s with %d values", name, len(sec.values))
+		return "created", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var current map[string]string
+	if json.Unmarshal([]byte(aws.StringValue(cur.SecretString)), &current) == nil && reflect.DeepEqual(current, sec.values) {
+		Log.Debugf("Secret %s is unchanged", name)
+		return "unchanged", nil
+	}
+	_, err = s.client.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:      aws.String(name),
+		SecretString:  aws.String(string(data)),
+		VersionStages: aws.StringSlice(append([]string{currentStage}, s.stages...)),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(tags) > 0 {
+		_, err := s.client.TagResource(&secretsmanager.TagResourceInput{SecretId: aws.String(name), Tags: tags})
+		if err != nil {
+			return "", fmt.Errorf("failed to tag: %w", err)
+		}
+	}
+	Log.Infof("Updated secret %s with %d values", name, len(sec.values))
+	return "updated", nil
+}
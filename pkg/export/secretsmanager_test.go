@@ -0,0 +1,134 @@
+package export
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/layout"
+)
+
+// Version IDs must be at least 32 characters.
+const (
+	smVersion1 = "11111111-1111-1111-1111-111111111111"
+	smVersion2 = "22222222-2222-2222-2222-222222222222"
+)
+
+// fakeSecretsManager keeps the current value of each secret and the
+// calls that changed them.
+type fakeSecretsManager struct {
+	t       *testing.T
+	secrets map[string]string
+	calls   []string
+	stages  []string
+}
+
+func (f *fakeSecretsManager) handle(op string, body []byte) interface{} {
+	var in struct {
+		SecretId      string
+		Name          string
+		SecretString  string
+		VersionStage  string
+		VersionStages []string
+	}
+	if err := json.Unmarshal(body, &in); err != nil {
+		f.t.Fatal(err)
+	}
+	if op != "GetSecretValue" {
+		f.calls = append(f.calls, op)
+	}
+	switch op {
+	case "GetSecretValue":
+		value, ok := f.secrets[in.SecretId]
+		if !ok {
+			return awsError("ResourceNotFoundException")
+		}
+		return map[string]string{"Name": in.SecretId, "SecretString": value, "VersionId": smVersion1}
+	case "CreateSecret":
+		f.secrets[in.Name] = in.SecretString
+		return map[string]string{"Name": in.Name, "VersionId": smVersion1}
+	case "PutSecretValue":
+		f.secrets[in.SecretId] = in.SecretString
+		f.stages = in.VersionStages
+		return map[string]string{"Name": in.SecretId, "VersionId": smVersion2}
+	case "UpdateSecretVersionStage":
+		f.stages = append(f.stages, in.VersionStage)
+		return map[string]string{"Name": in.SecretId}
+	case "TagResource":
+		return struct{}{}
+	}
+	f.t.Errorf("unexpected Secrets Manager call %s", op)
+	return awsError("InvalidAction")
+}
+
+// exportSecretsManager writes the variables of a compose service to a
+// fake Secrets Manager holding secrets and returns it.
+func exportSecretsManager(t *testing.T, secrets map[string]string) *fakeSecretsManager {
+	t.Helper()
+	f := &fakeSecretsManager{t: t, secrets: secrets}
+	srv := fakeJSONService(t, f.handle)
+
+	u, _ := url.Parse("secretsmanager://envs?stage=prod&endpoint=" + url.QueryEscape(srv.URL))
+	sink, err := newSecretsManagerSink(u, testOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctr := &Container{Container: inspector.Container{ID: "aaa111", Name: "shop_api_1", Labels: map[string]string{
+		layout.ProjectLabel: "shop",
+		layout.ServiceLabel: "api",
+	}}, Path: "shop/api"}
+	for _, item := range []Item{
+		{Key: "shop/api/LOG_LEVEL", Data: []byte("debug"), Variable: "LOG_LEVEL", Container: ctr},
+		{Key: "shop/api/DB_PASSWORD", Data: []byte("hunter2"), Variable: "DB_PASSWORD", Secret: true, Container: ctr},
+		{Key: "shop/api/API_TOKEN", Data: []byte("t0ken"), Variable: "API_TOKEN", Secret: true, Container: ctr},
+		{Key: "shop/api/" + MetaKey, Data: []byte("{}"), Meta: true, Container: ctr},
+	} {
+		if err := sink.Put(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+const smWant = `{"API_TOKEN":"t0ken","DB_PASSWORD":"hunter2"}`
+
+func TestSecretsManagerCreate(t *testing.T) {
+	f := exportSecretsManager(t, map[string]string{})
+
+	if want := map[string]string{"/envs/shop/api": smWant}; !reflect.DeepEqual(f.secrets, want) {
+		t.Errorf("secrets = %v, want %v", f.secrets, want)
+	}
+	if want := []string{"CreateSecret", "UpdateSecretVersionStage"}; !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %v, want %v", f.calls, want)
+	}
+	if want := []string{"prod"}; !reflect.DeepEqual(f.stages, want) {
+		t.Errorf("stages = %v, want %v", f.stages, want)
+	}
+}
+
+func TestSecretsManagerPutValue(t *testing.T) {
+	f := exportSecretsManager(t, map[string]string{"/envs/shop/api": `{"DB_PASSWORD":"old"}`})
+
+	if got := f.secrets["/envs/shop/api"]; got != smWant {
+		t.Errorf("secret = %s, want %s", got, smWant)
+	}
+	if want := []string{"PutSecretValue", "TagResource"}; !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %v, want %v", f.calls, want)
+	}
+	if want := []string{"AWSCURRENT", "prod"}; !reflect.DeepEqual(f.stages, want) {
+		t.Errorf("stages = %v, want %v", f.stages, want)
+	}
+}
+
+func TestSecretsManagerUnchanged(t *testing.T) {
+	f := exportSecretsManager(t, map[string]string{"/envs/shop/api": smWant})
+
+	if len(f.calls) != 0 {
+		t.Errorf("an unchanged secret was written: %v", f.calls)
+	}
+}
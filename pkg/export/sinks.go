@@ -0,0 +1,223 @@
+package export
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SinkFactory opens the sink a URI points to.
+type SinkFactory func(u *url.URL, opts Options) (Sink, error)
+
+var sinks = map[string]SinkFactory{}
+
+func init() {
+	RegisterSink("file", newFileSink)
+	RegisterSink("tar", newTarSink)
+}
+
+// RegisterSink makes f the factory for URIs with the given scheme.
+func RegisterSink(scheme string, f SinkFactory) {
+	sinks[scheme] = f
+}
+
+// SinkSchemes returns the registered URI schemes, sorted.
+func SinkSchemes() []string {
+	schemes := make([]string, 0, len(sinks))
+	for scheme := range sinks {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// OpenSink opens the sink for target: "-" for stdout, or a URI such as
+// file:///tmp/out or s3://bucket/prefix?sse=AES256. A target without a
+// scheme is a directory. The query string holds the sink's options.
+func OpenSink(target string, opts Options) (Sink, error) {
+	if target == "-" {
+		return &stdoutSink{w: os.Stdout}, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target '%s': %w", target, err)
+	}
+	if u.Scheme == "" {
+		u = &url.URL{Scheme: "file", Path: target}
+	}
+	f, ok := sinks[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown target '%s' (valid schemes: %v)", target, SinkSchemes())
+	}
+	return f(u, opts)
+}
+
+// queryOptions returns the options in u's query string, rejecting any
+// that aren't in known.
+func queryOptions(u *url.URL, known ...string) (url.Values, error) {
+	q := u.Query()
+	for name := range q {
+		ok := false
+		for _, k := range known {
+			ok = ok || name == k
+		}
+		if !ok && len(known) == 0 {
+			return nil, fmt.Errorf("%s:// takes no options, got '%s'", u.Scheme, name)
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown option '%s' for %s:// (valid: %s)", name, u.Scheme, strings.Join(known, ", "))
+		}
+	}
+	return q, nil
+}
+
+// Redact returns target without its query string, for logging.
+func Redact(target string) string {
+	if i := strings.IndexByte(target, '?'); i >= 0 {
+		return target[:i]
+	}
+	return target
+}
+
+// fileSink writes each item to a file under a directory. Items holding
+// secrets are only readable by the owner.
+type fileSink struct {
+	dir string
+}
+
+func newFileSink(u *url.URL, opts Options) (Sink, error) {
+	if _, err := queryOptions(u); err != nil {
+		return nil, err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("file URIs can't name a host, use file:///%s%s", u.Host, u.Path)
+	}
+	if u.Path == "" {
+		return nil, fmt.Errorf("file URIs need a directory, such as file:///tmp/dockerenv")
+	}
+	return &fileSink{dir: u.Path}, nil
+}
+
+func (s *fileSink) Put(item Item) error {
+	name := filepath.Join(s.dir, filepath.FromSlash(item.Key))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	mode := os.FileMode(0644)
+	if item.Secret {
+		mode = 0600
+	}
+	Log.Infof("Writing %d bytes to %s", len(item.Data), name)
+	if err := ioutil.WriteFile(name, item.Data, mode); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file.
+	return os.Chmod(name, mode)
+}
+
+func (s *fileSink) Close() error { return nil }
+
+// stdoutSink prints items once all of them are known, so a single item
+// is printed as is and several get a header each.
+type stdoutSink struct {
+	w     io.Writer
+	items []Item
+}
+
+func (s *stdoutSink) Put(item Item) error {
+	s.items = append(s.items, item)
+	return nil
+}
+
+func (s *stdoutSink) Close() error {
+	if len(s.items) == 1 {
+		_, err := s.w.Write(s.items[0].Data)
+		return err
+	}
+	var buf bytes.Buffer
+	for i, item := range s.items {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "==> %s <==\n", item.Key)
+		buf.Write(item.Data)
+		if len(item.Data) > 0 && item.Data[len(item.Data)-1] != '\n' {
+			buf.WriteString("\n")
+		}
+	}
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// tarSink collects items into a tar archive, gzipped when the file name
+// ends in .tgz or .gz, and writes it on Close.
+type tarSink struct {
+	name  string
+	buf   bytes.Buffer
+	tw    *tar.Writer
+	gz    *gzip.Writer
+	now   time.Time
+	count int
+}
+
+func newTarSink(u *url.URL, opts Options) (Sink, error) {
+	if _, err := queryOptions(u); err != nil {
+		return nil, err
+	}
+	if u.Host != "" || u.Path == "" {
+		return nil, fmt.Errorf("tar URIs need an absolute file name, such as tar:///tmp/dockerenv.tgz")
+	}
+	s := &tarSink{name: u.Path, now: time.Now()}
+	var w io.Writer = &s.buf
+	if strings.HasSuffix(s.name, ".tgz") || strings.HasSuffix(s.name, ".gz") {
+		s.gz = gzip.NewWriter(&s.buf)
+		w = s.gz
+	}
+	s.tw = tar.NewWriter(w)
+	return s, nil
+}
+
+func (s *tarSink) Put(item Item) error {
+	mode := int64(0644)
+	if item.Secret {
+		mode = 0600
+	}
+	hdr := &tar.Header{
+		Name:    item.Key,
+		Mode:    mode,
+		Size:    int64(len(item.Data)),
+		ModTime: s.now,
+	}
+	if err := s.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := s.tw.Write(item.Data)
+	s.count++
+	return err
+}
+
+func (s *tarSink) Close() error {
+	if err := s.tw.Close(); err != nil {
+		return err
+	}
+	if s.gz != nil {
+		if err := s.gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.name), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	Log.Infof("Writing %d files to %s", s.count, s.name)
+	// The archive holds secrets as often as not.
+	return ioutil.WriteFile(s.name, s.buf.Bytes(), 0600)
+}
@@ -0,0 +1,83 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/layout"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+// TestFileSinkModes checks that every file holding a value is readable by
+// its owner only, whatever the format.
+func TestFileSinkModes(t *testing.T) {
+	const password = "correct-horse-battery"
+	ctr := inspector.Container{ID: "aaa111aaa111", Name: "/api", Host: "local", Env: []inspector.EnvVar{
+		{Name: "LOG_LEVEL", Value: "debug"},
+		{Name: "DATABASE_URL", Value: "postgres://app:" + password + "@db/app"},
+	}}
+	batch := &Batch{
+		Containers: []*Container{{Container: ctr, Path: "api"}},
+		Index:      layout.Index{},
+		Classifier: secrets.Default,
+	}
+	batch.Index.Add(ctr, "api")
+	opts := testOptions{"env-dialect": "compose", "value-policy": "reveal", "rfc4180": true, "systemd-unit": "none"}
+
+	for _, name := range []string{"env", "json", "yaml", "csv", "tsv", "systemd"} {
+		f, err := LookupFormat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmtr, err := f.New(opts)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		items, err := fmtr.Format(batch)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		dir := t.TempDir()
+		sink, err := OpenSink("file://"+dir, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			if err := sink.Put(item); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		found := false
+		err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if strings.Contains(string(data), password) {
+				found = true
+				if mode := fi.Mode().Perm(); mode != 0600 {
+					t.Errorf("--format %s wrote %s with mode %o, want 0600", name, path, mode)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			t.Errorf("--format %s wrote no file holding the value", name)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package export
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+func init() {
+	RegisterSink("ssm", newSSMSink)
+}
+
+// ssmSink stores key/value items as SSM parameters: SecureString for
+// secrets, encrypted with kms_key when given, and String otherwise.
+// Existing parameters are only changed with --overwrite. Parameters are
+// written on Close, after their current values are read in batches, and
+// calls are retried with backoff when throttled.
+//
+// Options: region, endpoint and kms_key.
+type ssmSink struct {
+	prefix    string
+	kmsKey    string
+	overwrite bool
+	client    *ssm.SSM
+	items     []Item
+	// empty counts items skipped because SSM can't store empty values.
+	empty int
+}
+
+// ssmGetBatch is the most parameters GetParameters accepts.
+const ssmGetBatch = 10
+
+func newSSMSink(u *url.URL, opts Options) (Sink, error) {
+	q, err := queryOptions(u, "region", "endpoint", "kms_key")
+	if err != nil {
+		return nil, err
+	}
+	sess, err := newAWSSession(q)
+	if err != nil {
+		return nil, err
+	}
+	return &ssmSink{
+		prefix:    path.Join("/", u.Host, u.Path),
+		kmsKey:    q.Get("kms_key"),
+		overwrite: opts.Bool("overwrite"),
+		client:    ssm.New(sess),
+	}, nil
+}
+
+func (s *ssmSink) Put(item Item) error {
+	if item.Variable == "" && !item.Meta {
+		return fmt.Errorf("%s: SSM stores key/value items only, use --format kv", item.Key)
+	}
+	if len(item.Data) == 0 {
+		Log.Warningf("skipping %s: SSM can't store empty values", item.Key)
+		s.empty++
+		return nil
+	}
+	s.items = append(s.items, item)
+	return nil
+}
+
+func (s *ssmSink) Close() error {
+	names := make([]string, len(s.items))
+	for i, item := range s.items {
+		names[i] = path.Join(s.prefix, item.Key)
+	}
+	current, err := s.current(names)
+	if err != nil {
+		return err
+	}
+
+	var created, updated, unchanged, failed int
+	skipped := s.empty
+	for i, item := range s.items {
+		name := names[i]
+		typ := ssm.ParameterTypeString
+		if item.Secret {
+			typ = ssm.ParameterTypeSecureString
+		}
+		old, exists := current[name]
+		switch {
+		case exists && aws.StringValue(old.Type) == typ && aws.StringValue(old.Value) == string(item.Data):
+			unchanged++
+			continue
+		case exists && !s.overwrite:
+			Log.Warningf("%s already exists with a different value; use --overwrite to replace it", name)
+			skipped++
+			continue
+		}
+
+		input := &ssm.PutParameterInput{
+			Name:      aws.String(name),
+			Type:      aws.String(typ),
+			Value:     aws.String(string(item.Data)),
+			Overwrite: aws.Bool(exists),
+		}
+		if item.Secret && s.kmsKey != "" {
+			input.KeyId = aws.String(s.kmsKey)
+		}
+		err := withBackoff(func() error {
+			_, err := s.client.PutParameter(input)
+			return err
+		}, ssm.ErrCodeTooManyUpdates)
+		if err == nil {
+			err = s.tag(name, item)
+		}
+		if err != nil {
+			Log.Errorf("failed to write %s: %s", name, err)
+			failed++
+			continue
+		}
+		if exists {
+			Log.Infof("Updated %s (%s)", name, typ)
+			updated++
+		} else {
+			Log.Infof("Created %s (%s)", name, typ)
+			created++
+		}
+	}
+
+	Log.Infof("SSM %s: %d created, %d updated, %d unchanged, %d skipped, %d failed",
+		s.prefix, created, updated, unchanged, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d parameters could not be written", failed, len(s.items))
+	}
+	return nil
+}
+
+// current returns the parameters among names that already exist.
+func (s *ssmSink) current(names []string) (map[string]*ssm.Parameter, error) {
+	params := map[string]*ssm.Parameter{}
+	for start := 0; start < len(names); start += ssmGetBatch {
+		end := start + ssmGetBatch
+		if end > len(names) {
+			end = len(names)
+		}
+		var out *ssm.GetParametersOutput
+		err := withBackoff(func() error {
+			var err error
+			out, err = s.client.GetParameters(&ssm.GetParametersInput{
+				Names:          aws.StringSlice(names[start:end]),
+				WithDecryption: aws.Bool(true),
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing parameters: %w", err)
+		}
+		for _, p := range out.Parameters {
+			params[aws.StringValue(p.Name)] = p
+		}
+	}
+	return params, nil
+}
+
+// tag adds the labels of the item's container to the parameter. Labels
+// SSM can't store as tags are left out.
+func (s *ssmSink) tag(name string, item Item) error {
+	if item.Container == nil || len(item.Container.Labels) == 0 {
+		return nil
+	}
+	var tags []*ssm.Tag
+	for _, kv := range labelTags(name, item.Container.Labels, maxTags) {
+		tags = append(tags, &ssm.Tag{Key: aws.String(kv[0]), Value: aws.String(kv[1])})
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return withBackoff(func() error {
+		_, err := s.client.AddTagsToResource(&ssm.AddTagsToResourceInput{
+			ResourceId:   aws.String(name),
+			ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+			Tags:         tags,
+		})
+		return err
+	}, ssm.ErrCodeTooManyUpdates)
+}
@@ -0,0 +1,148 @@
+package export
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+type fakeParam struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// fakeSSM is an in-memory parameter store.
+type fakeSSM struct {
+	t      *testing.T
+	params map[string]fakeParam
+	puts   []string
+	tagged []string
+}
+
+func (f *fakeSSM) handle(op string, body []byte) interface{} {
+	switch op {
+	case "GetParameters":
+		var in struct{ Names []string }
+		if err := json.Unmarshal(body, &in); err != nil {
+			f.t.Fatal(err)
+		}
+		out := struct {
+			Parameters        []fakeParam
+			InvalidParameters []string
+		}{Parameters: []fakeParam{}, InvalidParameters: []string{}}
+		for _, name := range in.Names {
+			if p, ok := f.params[name]; ok {
+				out.Parameters = append(out.Parameters, p)
+			} else {
+				out.InvalidParameters = append(out.InvalidParameters, name)
+			}
+		}
+		return out
+	case "PutParameter":
+		var in struct {
+			fakeParam
+			Overwrite bool
+		}
+		if err := json.Unmarshal(body, &in); err != nil {
+			f.t.Fatal(err)
+		}
+		if _, exists := f.params[in.Name]; exists && !in.Overwrite {
+			return awsError("ParameterAlreadyExists")
+		}
+		f.params[in.Name] = in.fakeParam
+		f.puts = append(f.puts, in.Name)
+		return map[string]int{"Version": 1}
+	case "AddTagsToResource":
+		var in struct{ ResourceId string }
+		if err := json.Unmarshal(body, &in); err != nil {
+			f.t.Fatal(err)
+		}
+		f.tagged = append(f.tagged, in.ResourceId)
+		return struct{}{}
+	}
+	f.t.Errorf("unexpected SSM call %s", op)
+	return awsError("InvalidAction")
+}
+
+// exportSSM writes LOG_LEVEL and DB_PASSWORD of one container to a fake
+// SSM holding params and returns it.
+func exportSSM(t *testing.T, params map[string]fakeParam, overwrite bool) *fakeSSM {
+	t.Helper()
+	f := &fakeSSM{t: t, params: params}
+	srv := fakeJSONService(t, f.handle)
+
+	u, _ := url.Parse("ssm:///prod?endpoint=" + url.QueryEscape(srv.URL))
+	sink, err := newSSMSink(u, testOptions{"overwrite": overwrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctr := &Container{Container: inspector.Container{ID: "aaa111", Name: "api", Labels: map[string]string{"team": "shop"}}, Path: "api"}
+	for _, item := range []Item{
+		{Key: "api/LOG_LEVEL", Data: []byte("debug"), Variable: "LOG_LEVEL", Container: ctr},
+		{Key: "api/DB_PASSWORD", Data: []byte("hunter2"), Variable: "DB_PASSWORD", Secret: true, Container: ctr},
+		{Key: "api/EMPTY", Data: nil, Variable: "EMPTY", Container: ctr},
+	} {
+		if err := sink.Put(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(f.puts)
+	sort.Strings(f.tagged)
+	return f
+}
+
+func TestSSMCreate(t *testing.T) {
+	f := exportSSM(t, map[string]fakeParam{}, false)
+
+	want := map[string]fakeParam{
+		"/prod/api/LOG_LEVEL":   {Name: "/prod/api/LOG_LEVEL", Type: "String", Value: "debug"},
+		"/prod/api/DB_PASSWORD": {Name: "/prod/api/DB_PASSWORD", Type: "SecureString", Value: "hunter2"},
+	}
+	if !reflect.DeepEqual(f.params, want) {
+		t.Errorf("parameters = %v, want %v", f.params, want)
+	}
+	if want := []string{"/prod/api/DB_PASSWORD", "/prod/api/LOG_LEVEL"}; !reflect.DeepEqual(f.tagged, want) {
+		t.Errorf("tagged %v, want %v", f.tagged, want)
+	}
+}
+
+func TestSSMUnchanged(t *testing.T) {
+	f := exportSSM(t, map[string]fakeParam{
+		"/prod/api/LOG_LEVEL":   {Name: "/prod/api/LOG_LEVEL", Type: "String", Value: "debug"},
+		"/prod/api/DB_PASSWORD": {Name: "/prod/api/DB_PASSWORD", Type: "SecureString", Value: "hunter2"},
+	}, true)
+
+	if len(f.puts) != 0 {
+		t.Errorf("unchanged parameters were written: %v", f.puts)
+	}
+}
+
+func TestSSMUpdate(t *testing.T) {
+	existing := func() map[string]fakeParam {
+		return map[string]fakeParam{
+			"/prod/api/LOG_LEVEL":   {Name: "/prod/api/LOG_LEVEL", Type: "String", Value: "info"},
+			"/prod/api/DB_PASSWORD": {Name: "/prod/api/DB_PASSWORD", Type: "SecureString", Value: "hunter2"},
+		}
+	}
+
+	f := exportSSM(t, existing(), false)
+	if len(f.puts) != 0 || f.params["/prod/api/LOG_LEVEL"].Value != "info" {
+		t.Errorf("a changed parameter was replaced without --overwrite: %v", f.puts)
+	}
+
+	f = exportSSM(t, existing(), true)
+	if want := []string{"/prod/api/LOG_LEVEL"}; !reflect.DeepEqual(f.puts, want) {
+		t.Errorf("with --overwrite wrote %v, want %v", f.puts, want)
+	}
+	if got := f.params["/prod/api/LOG_LEVEL"].Value; got != "debug" {
+		t.Errorf("LOG_LEVEL = %q after --overwrite, want debug", got)
+	}
+}
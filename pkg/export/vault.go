@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterSink("vault", newVaultSink)
+}
+
+// vaultSink writes to a Vault KV secrets engine. Key/value items are
+// stored as one secret per container, with a field per variable; other
+// items as a secret at their key with the content in a "value" field.
+// The token is read from VAULT_TOKEN and never from the URI.
+//
+// Options: addr (default VAULT_ADDR), namespace (default VAULT_NAMESPACE)
+// and kv (the engine version, 1 or 2; default 2).
+type vaultSink struct {
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	prefix    string
+	kv        string
+	client    *http.Client
+	// vars holds key/value items by secret path until Close.
+	vars map[string]map[string]string
+}
+
+func newVaultSink(u *url.URL, opts Options) (Sink, error) {
+	q, err := queryOptions(u, "addr", "namespace", "kv")
+	if err != nil {
+		return nil, err
+	}
+	s := &vaultSink{
+		addr:      q.Get("addr"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: q.Get("namespace"),
+		mount:     u.Host,
+		prefix:    strings.Trim(u.Path, "/"),
+		kv:        q.Get("kv"),
+		client:    http.DefaultClient,
+		vars:      map[string]map[string]string{},
+	}
+	if s.addr == "" {
+		s.addr = os.Getenv("VAULT_ADDR")
+	}
+	if s.namespace == "" {
+		s.namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if s.kv == "" {
+		s.kv = "2"
+	}
+	switch {
+	case s.mount == "":
+		return nil, fmt.Errorf("vault URIs need a mount, such as vault://secret/dockerenv")
+	case s.addr == "":
+		return nil, fmt.Errorf("vault needs an address: set VAULT_ADDR or ?addr=")
+	case s.token == "":
+		return nil, fmt.Errorf("vault needs a token: set VAULT_TOKEN")
+	case s.kv != "1" && s.kv != "2":
+		return nil, fmt.Errorf("unknown kv '%s' (valid: 1, 2)", s.kv)
+	}
+	return s, nil
+}
+
+func (s *vaultSink) Put(item Item) error {
+	if item.Variable != "" {
+		dir := path.Dir(item.Key)
+		if s.vars[dir] == nil {
+			s.vars[dir] = map[string]string{}
+		}
+		s.vars[dir][item.Variable] = string(item.Data)
+		return nil
+	}
+	return s.write(item.Key, map[string]string{"value": string(item.Data)})
+}
+
+func (s *vaultSink) Close() error {
+	dirs := make([]string, 0, len(s.vars))
+	for dir := range s.vars {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	var failed []string
+	for _, dir := range dirs {
+		if err := s.write(dir, s.vars[dir]); err != nil {
+			Log.Errorf("failed to write %s: %s", dir, err)
+			failed = append(failed, dir)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to write %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// write stores data as the secret at key.
+func (s *vaultSink) write(key string, data map[string]string) error {
+	var body interface{} = data
+	p := path.Join(s.mount, s.prefix, key)
+	if s.kv == "2" {
+		body = map[string]interface{}{"data": data}
+		p = path.Join(s.mount, "data", s.prefix, key)
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	Log.Infof("Writing %d fields to vault %s", len(data), p)
+	req, err := http.NewRequest(http.MethodPut, strings.TrimRight(s.addr, "/")+"/v1/"+p, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	req.Header.Set("Content-Type", "application/json")
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Package hcl writes the small subset of HCL used by the Terraform and
+// Nomad exporters: quoted strings, lists and aligned attributes.
+package hcl
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var ident = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// IsIdent reports whether s can be used as an unquoted name.
+func IsIdent(s string) bool {
+	return ident.MatchString(s)
+}
+
+// Quote returns s as a string literal. Template sequences are escaped so
+// the value is taken literally.
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '$', '%':
+			b.WriteRune(r)
+			if strings.HasPrefix(s[i+1:], "{") {
+				b.WriteRune(r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// List returns items as a list of string literals.
+func List(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = Quote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// Attr is a name and an already encoded value.
+type Attr struct {
+	Name  string
+	Value string
+}
+
+// WriteAttrs writes attrs one per line at the given indent, with the
+// equals signs aligned as `terraform fmt` does. Names that aren't
+// identifiers are quoted.
+func WriteAttrs(w io.Writer, indent string, attrs []Attr) error {
+	width := 0
+	names := make([]string, len(attrs))
+	for i, a := range attrs {
+		names[i] = a.Name
+		if !IsIdent(a.Name) {
+			names[i] = Quote(a.Name)
+		}
+		if len(names[i]) > width {
+			width = len(names[i])
+		}
+	}
+	for i, a := range attrs {
+		if _, err := fmt.Fprintf(w, "%s%-*s = %s\n", indent, width, names[i], a.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 )
 
@@ -14,10 +15,15 @@ type DockerInspector struct {
 	c *client.Client
 }
 
-func newDockerInspector() (Inspector, error) {
+func newDockerInspector(host string) (Inspector, error) {
 	di := &DockerInspector{}
 
-	c, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+
+	c, err := client.NewClientWithOpts(opts...)
 	if err != nil || c == nil {
 		return di, fmt.Errorf("error initializing docker client: %s", err)
 	}
@@ -27,6 +33,11 @@ func newDockerInspector() (Inspector, error) {
 	}, nil
 }
 
+// Host implements Inspector.
+func (di *DockerInspector) Host() string {
+	return di.c.DaemonHost()
+}
+
 // GetValue implements Inspector.
 func (di *DockerInspector) GetValue(containerId, varName string) (string, error) {
 	values, err := di.GetAllValues(containerId)
@@ -52,6 +63,137 @@ func (di *DockerInspector) GetAllValues(containerId string) (map[string]string,
 	return values, nil
 }
 
+// ListContainers implements Inspector. Filtering happens in the daemon, so
+// only matching containers are returned. A status filter includes stopped
+// containers, which are otherwise omitted.
+func (di *DockerInspector) ListContainers(filters ...string) ([]Container, error) {
+	opts, err := listOptions(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := di.c.ContainerList(context.TODO(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("error listing containers: %s", err)
+	}
+
+	containers := make([]Container, 0, len(list))
+	for _, c := range list {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		containers = append(containers, Container{
+			ID:     c.ID,
+			Name:   name,
+			Image:  c.Image,
+			State:  c.State,
+			Host:   di.Host(),
+			Labels: c.Labels,
+		})
+	}
+	return containers, nil
+}
+
+// Inspect implements Inspector.
+func (di *DockerInspector) Inspect(containerId string) (Container, error) {
+	data, err := di.inspect(containerId)
+	if err != nil {
+		return Container{}, fmt.Errorf("error inspecting container '%s': %s", containerId, err)
+	}
+
+	ctr := Container{
+		ID:     data.ID,
+		Name:   strings.TrimPrefix(data.Name, "/"),
+		Host:   di.Host(),
+		Labels: map[string]string{},
+	}
+	if data.State != nil {
+		ctr.State = data.State.Status
+	}
+	if data.Config != nil {
+		ctr.Image = data.Config.Image
+		for k, v := range data.Config.Labels {
+			ctr.Labels[k] = v
+		}
+		imageEnv := di.imageEnv(data.Image)
+		for _, kv := range data.Config.Env {
+			x := strings.SplitN(kv, "=", 2)
+			ev := EnvVar{Name: x[0]}
+			if len(x) == 2 {
+				ev.Value = x[1]
+			}
+			if imageEnv != nil {
+				ev.Source = SourceContainer
+				if imageEnv[kv] {
+					ev.Source = SourceImage
+				}
+			}
+			ctr.Env = append(ctr.Env, ev)
+		}
+	}
+	return ctr, nil
+}
+
+// InspectRaw implements Inspector.
+func (di *DockerInspector) InspectRaw(containerId string) (types.ContainerJSON, error) {
+	data, err := di.inspect(containerId)
+	if err != nil {
+		return data, fmt.Errorf("error inspecting container '%s': %s", containerId, err)
+	}
+	return data, nil
+}
+
+// InspectImage implements Inspector.
+func (di *DockerInspector) InspectImage(imageId string) (types.ImageInspect, error) {
+	img, _, err := di.c.ImageInspectWithRaw(context.TODO(), imageId)
+	if err != nil {
+		return img, fmt.Errorf("error inspecting image '%s': %s", imageId, err)
+	}
+	return img, nil
+}
+
+// imageEnv returns the set of NAME=value entries defined by an image, or
+// nil if the image can't be inspected (e.g. it was removed).
+func (di *DockerInspector) imageEnv(imageId string) map[string]bool {
+	img, _, err := di.c.ImageInspectWithRaw(context.TODO(), imageId)
+	if err != nil || img.Config == nil {
+		return nil
+	}
+	env := map[string]bool{}
+	for _, kv := range img.Config.Env {
+		env[kv] = true
+	}
+	return env
+}
+
+func listOptions(kvs []string) (types.ContainerListOptions, error) {
+	opts := types.ContainerListOptions{Filters: filters.NewArgs()}
+	for _, kv := range kvs {
+		x := strings.SplitN(kv, "=", 2)
+		if len(x) != 2 || x[1] == "" {
+			return opts, fmt.Errorf("invalid filter '%s': expected key=value", kv)
+		}
+		if !validFilterKey(x[0]) {
+			return opts, fmt.Errorf("invalid filter '%s': key must be one of %s", kv, strings.Join(FilterKeys, ", "))
+		}
+		if x[0] == "status" {
+			opts.All = true
+		}
+		opts.Filters.Add(x[0], x[1])
+	}
+	return opts, nil
+}
+
+func validFilterKey(key string) bool {
+	for _, k := range FilterKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
 func (di *DockerInspector) inspect(containerId string) (types.ContainerJSON, error) {
 	return di.c.ContainerInspect(context.TODO(), containerId)
 }
@@ -1,13 +1,80 @@
 package inspector
 
+import (
+	"github.com/docker/docker/api/types"
+)
+
 type Inspector interface {
 	// GetAll returns a map of all environment vars for a container.
 	GetAllValues(containerId string) (map[string]string, error)
 
 	// Returns the raw string value of the variable
 	GetValue(containerId, varName string) (string, error)
+
+	// ListContainers returns a summary of each running container matching
+	// all of the given "key=value" filters (see FilterKeys). The Env of
+	// each summary is left empty; use Inspect for that.
+	ListContainers(filters ...string) ([]Container, error)
+
+	// Inspect returns the metadata and ordered environment of a container.
+	Inspect(containerId string) (Container, error)
+
+	// InspectRaw returns the daemon's full description of a container.
+	InspectRaw(containerId string) (types.ContainerJSON, error)
+
+	// InspectImage returns the daemon's description of an image.
+	InspectImage(imageId string) (types.ImageInspect, error)
+
+	// Host returns the address of the daemon being inspected.
+	Host() string
+}
+
+// Container describes a container and its environment in the order the
+// runtime reports it.
+type Container struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Image  string            `json:"image"`
+	State  string            `json:"state"`
+	Host   string            `json:"host"`
+	Labels map[string]string `json:"labels"`
+	Env    []EnvVar          `json:"env"`
 }
 
+// EnvVar is a single NAME=value pair from a container's environment.
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Source is SourceImage or SourceContainer, or empty if unknown.
+	Source string `json:"source,omitempty"`
+}
+
+const (
+	// SourceImage marks a variable inherited unchanged from the image.
+	SourceImage = "image"
+	// SourceContainer marks a variable set or overridden when the
+	// container was created.
+	SourceContainer = "container"
+)
+
+// ShortID returns the abbreviated container ID used in paths and tables.
+func (c Container) ShortID() string {
+	if len(c.ID) > 8 {
+		return c.ID[0:8]
+	}
+	return c.ID
+}
+
+// FilterKeys are the Engine API container filters accepted by ListContainers.
+var FilterKeys = []string{"id", "label", "name", "ancestor", "network", "status", "health"}
+
 func New() (Inspector, error) {
-	return newDockerInspector()
+	return newDockerInspector("")
+}
+
+// NewForHost returns an Inspector for the daemon at host (e.g.
+// "unix:///var/run/docker.sock" or "tcp://10.0.0.5:2376"). An empty host
+// uses the DOCKER_HOST environment, like New.
+func NewForHost(host string) (Inspector, error) {
+	return newDockerInspector(host)
 }
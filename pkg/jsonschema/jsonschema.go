@@ -0,0 +1,206 @@
+// Package jsonschema validates JSON documents against the subset of JSON
+// Schema (draft-07) used by the schemas published with dockerenv:
+// $ref to local definitions, type, enum, const, pattern, minLength,
+// properties, required, additionalProperties and items.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a compiled schema document.
+type Schema struct {
+	root map[string]interface{}
+}
+
+// Parse reads a schema document.
+func Parse(data []byte) (*Schema, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// ValidationError lists every problem found in a document.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("document does not match schema:\n  %s", strings.Join(e.Problems, "\n  "))
+}
+
+// Validate checks raw JSON against the schema.
+func (s *Schema) Validate(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	var problems []string
+	s.validate(s.root, doc, "", &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (s *Schema) resolve(ref string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref '%s'", ref)
+	}
+	var node interface{} = s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref '%s'", ref)
+		}
+		node = m[part]
+	}
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unresolvable $ref '%s'", ref)
+	}
+	return m, nil
+}
+
+func (s *Schema) validate(schema map[string]interface{}, v interface{}, path string, problems *[]string) {
+	fail := func(format string, args ...interface{}) {
+		p := path
+		if p == "" {
+			p = "/"
+		}
+		*problems = append(*problems, p+": "+fmt.Sprintf(format, args...))
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := s.resolve(ref)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		schema = resolved
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, v) {
+		fail("expected %v, got %s", t, typeOf(v))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %v is not one of %v", v, enum)
+		}
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		fail("value %v must be %v", v, c)
+	}
+
+	switch val := v.(type) {
+	case string:
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				fail("invalid pattern '%s' in schema", pattern)
+			} else if !re.MatchString(val) {
+				fail("'%s' does not match pattern '%s'", val, pattern)
+			}
+		}
+		if min, ok := schema["minLength"].(float64); ok && float64(len([]rune(val))) < min {
+			fail("shorter than %v characters", min)
+		}
+
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, _ := r.(string); name != "" {
+					if _, present := val[name]; !present {
+						fail("missing required property '%s'", name)
+					}
+				}
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "/" + k
+			if ps, ok := props[k].(map[string]interface{}); ok {
+				s.validate(ps, val[k], child, problems)
+				continue
+			}
+			switch ap := schema["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					fail("unexpected property '%s'", k)
+				}
+			case map[string]interface{}:
+				s.validate(ap, val[k], child, problems)
+			}
+		}
+
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				s.validate(items, item, fmt.Sprintf("%s/%d", path, i), problems)
+			}
+		}
+	}
+}
+
+func matchesType(t interface{}, v interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		return typeMatches(tt, v)
+	case []interface{}:
+		for _, one := range tt {
+			if name, ok := one.(string); ok && typeMatches(name, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeMatches(name string, v interface{}) bool {
+	actual := typeOf(v)
+	if name == "number" && actual == "integer" {
+		return true
+	}
+	return name == actual
+}
+
+func typeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if val == float64(int64(val)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,111 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cmattoon/dockerenv/pkg/envdoc"
+	"github.com/cmattoon/dockerenv/pkg/export"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/jsonschema"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+// noOptions leaves every setting at its zero value.
+type noOptions struct{}
+
+func (noOptions) String(string) string        { return "" }
+func (noOptions) StringSlice(string) []string { return nil }
+func (noOptions) Bool(string) bool            { return false }
+func (noOptions) Int(string) int              { return 0 }
+
+// exportJSON returns what `export --format json` writes for one container.
+func exportJSON(t *testing.T) []byte {
+	t.Helper()
+	f, err := export.LookupFormat("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmtr, err := f.New(noOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctr := inspector.Container{
+		ID: "aaa111aaa111", Name: "/api", Image: "shop/api:1.2", State: "running", Host: "unix:///var/run/docker.sock",
+		Labels: map[string]string{"com.docker.compose.service": "api"},
+		Env: []inspector.EnvVar{
+			{Name: "PATH", Value: "/usr/bin", Source: inspector.SourceImage},
+			{Name: "LOG_LEVEL", Value: "debug", Source: inspector.SourceContainer},
+		},
+	}
+	items, err := fmtr.Format(&export.Batch{
+		Containers: []*export.Container{{Container: ctr, Path: "shop/api", Snapshot: &envdoc.Snapshot{Cmd: []string{"serve"}}}},
+		Classifier: secrets.Default,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return items[0].Data
+}
+
+// edit returns data with change applied to its decoded form.
+func edit(t *testing.T, data []byte, change func(doc map[string]interface{})) []byte {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	change(doc)
+	out, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func container(doc map[string]interface{}) map[string]interface{} {
+	host := doc["hosts"].([]interface{})[0].(map[string]interface{})
+	return host["containers"].([]interface{})[0].(map[string]interface{})
+}
+
+func TestExportSchema(t *testing.T) {
+	schema, err := jsonschema.Parse(envdoc.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := exportJSON(t)
+	if err := schema.Validate(data); err != nil {
+		t.Fatalf("a real export doesn't validate: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(doc map[string]interface{})
+		want   string
+	}{
+		{"wrong schemaVersion", func(doc map[string]interface{}) { doc["schemaVersion"] = "2" }, "/schemaVersion: value 2 must be 1"},
+		{"schemaVersion as a number", func(doc map[string]interface{}) { doc["schemaVersion"] = 1 }, "/schemaVersion: value 1 must be 1"},
+		{"missing hosts", func(doc map[string]interface{}) { delete(doc, "hosts") }, "/: missing required property 'hosts'"},
+		{"missing image", func(doc map[string]interface{}) { delete(container(doc), "image") }, "/hosts/0/containers/0: missing required property 'image'"},
+		{"bad id", func(doc map[string]interface{}) { container(doc)["id"] = "not-hex" }, "/hosts/0/containers/0/id: 'not-hex' does not match pattern"},
+		{"env of the wrong type", func(doc map[string]interface{}) { container(doc)["env"] = "PATH=/usr/bin" }, "/hosts/0/containers/0/env: expected array, got string"},
+		{"unknown property", func(doc map[string]interface{}) { doc["extra"] = true }, "/: unexpected property 'extra'"},
+	}
+	for _, tt := range tests {
+		err := schema.Validate(edit(t, data, tt.change))
+		var verr *jsonschema.ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: Validate returned %v, want a ValidationError", tt.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Validate returned %q, want it to mention %q", tt.name, err, tt.want)
+		}
+	}
+
+	if err := schema.Validate([]byte("{")); err == nil {
+		t.Error("Validate accepted invalid JSON")
+	}
+}
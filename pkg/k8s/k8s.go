@@ -0,0 +1,159 @@
+// Package k8s converts a container's environment into Kubernetes
+// ConfigMap and Secret manifests.
+package k8s
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/layout"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+// Options controls naming of the generated objects.
+type Options struct {
+	// Name is the base name of the objects. ConfigMaps get a "-config"
+	// suffix and Secrets a "-secrets" suffix.
+	Name string
+	// Namespace is omitted from the manifests when empty.
+	Namespace string
+	// Labels are added to every object, after the derived labels.
+	Labels map[string]string
+}
+
+// DefaultOptions derives the name, namespace and labels of a container
+// from its compose labels. The name is the (already rendered) layout path.
+func DefaultOptions(ctr inspector.Container, name string) Options {
+	d := layout.NewData(ctr)
+	opts := Options{
+		Name:      Name(name),
+		Namespace: Name(d.Project),
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "dockerenv",
+		},
+	}
+	if d.Service != "" {
+		opts.Labels["app.kubernetes.io/name"] = Name(d.Service)
+	} else {
+		opts.Labels["app.kubernetes.io/name"] = Name(ctr.Name)
+	}
+	if d.Project != "" {
+		opts.Labels["app.kubernetes.io/part-of"] = Name(d.Project)
+	}
+	return opts
+}
+
+var (
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	validKey         = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+)
+
+// Name converts s to a valid DNS-1123 label.
+func Name(s string) string {
+	n := invalidNameChars.ReplaceAllString(strings.ToLower(s), "-")
+	n = strings.Trim(n, "-")
+	if len(n) > 54 {
+		// leave room for the "-secrets" suffix
+		n = strings.TrimRight(n[:54], "-")
+	}
+	return n
+}
+
+type metadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type configMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   metadata          `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type secret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   metadata          `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type ref struct {
+	Name string `yaml:"name"`
+}
+
+type envFromSource struct {
+	ConfigMapRef *ref `yaml:"configMapRef,omitempty"`
+	SecretRef    *ref `yaml:"secretRef,omitempty"`
+}
+
+// Result holds the generated YAML documents.
+type Result struct {
+	// Manifests contains the ConfigMap and Secret, separated by "---".
+	Manifests []byte
+	// EnvFrom is a container spec snippet referencing them.
+	EnvFrom []byte
+	// Skipped lists variables whose names are not valid ConfigMap keys.
+	Skipped []string
+}
+
+// Generate splits the environment of ctr with cls and renders the objects.
+// Empty objects are left out.
+func Generate(ctr inspector.Container, cls *secrets.Classifier, opts Options) (*Result, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("no kubernetes name for container %s", ctr.ShortID())
+	}
+	meta := func(suffix string) metadata {
+		return metadata{Name: opts.Name + suffix, Namespace: opts.Namespace, Labels: opts.Labels}
+	}
+
+	res := &Result{}
+	config := map[string]string{}
+	secretData := map[string]string{}
+	for _, ev := range ctr.Env {
+		if !validKey.MatchString(ev.Name) {
+			res.Skipped = append(res.Skipped, ev.Name)
+			continue
+		}
+		if cls.IsSecret(ev.Name, ev.Value) {
+			secretData[ev.Name] = base64.StdEncoding.EncodeToString([]byte(ev.Value))
+		} else {
+			config[ev.Name] = ev.Value
+		}
+	}
+
+	var docs [][]byte
+	var envFrom []envFromSource
+	if len(config) > 0 {
+		b, err := yaml.Marshal(configMap{APIVersion: "v1", Kind: "ConfigMap", Metadata: meta("-config"), Data: config})
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, b)
+		envFrom = append(envFrom, envFromSource{ConfigMapRef: &ref{Name: opts.Name + "-config"}})
+	}
+	if len(secretData) > 0 {
+		b, err := yaml.Marshal(secret{APIVersion: "v1", Kind: "Secret", Metadata: meta("-secrets"), Type: "Opaque", Data: secretData})
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, b)
+		envFrom = append(envFrom, envFromSource{SecretRef: &ref{Name: opts.Name + "-secrets"}})
+	}
+
+	res.Manifests = bytes.Join(docs, []byte("---\n"))
+	snippet, err := yaml.Marshal(map[string][]envFromSource{"envFrom": envFrom})
+	if err != nil {
+		return nil, err
+	}
+	res.EnvFrom = snippet
+	return res, nil
+}
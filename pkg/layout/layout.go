@@ -0,0 +1,129 @@
+// Package layout decides where each container's exported files live.
+package layout
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+const (
+	// ProjectLabel and ServiceLabel are set by docker-compose.
+	ProjectLabel = "com.docker.compose.project"
+	ServiceLabel = "com.docker.compose.service"
+	NumberLabel  = "com.docker.compose.container-number"
+)
+
+// Presets are the named layouts accepted by Parse.
+var Presets = map[string]string{
+	// id keys containers by short ID. This changes on every redeploy.
+	"id": "{{.ShortID}}",
+	// compose groups containers by project and service, falling back to
+	// the container name for containers not started by compose.
+	"compose": "{{if .Service}}{{.Project}}/{{.Service}}{{with .Replica}}-{{.}}{{end}}{{else}}{{.Name}}{{end}}",
+}
+
+// Data is the template data for a layout.
+type Data struct {
+	ID      string
+	ShortID string
+	Name    string
+	Image   string
+	Labels  map[string]string
+	Project string
+	Service string
+	// Replica is the compose container number when it is greater than 1.
+	Replica string
+}
+
+// NewData builds the layout data for a container.
+func NewData(ctr inspector.Container) Data {
+	d := Data{
+		ID:      ctr.ID,
+		ShortID: ctr.ShortID(),
+		Name:    ctr.Name,
+		Image:   ctr.Image,
+		Labels:  ctr.Labels,
+		Project: ctr.Labels[ProjectLabel],
+		Service: ctr.Labels[ServiceLabel],
+	}
+	if n := ctr.Labels[NumberLabel]; n != "" && n != "1" {
+		d.Replica = n
+	}
+	return d
+}
+
+// Layout maps containers to relative paths.
+type Layout struct {
+	t *template.Template
+}
+
+// Parse accepts a preset name or a Go template such as
+// "{{.Project}}/{{.Name}}".
+func Parse(spec string) (*Layout, error) {
+	if spec == "" {
+		spec = "id"
+	}
+	if preset, ok := Presets[spec]; ok {
+		spec = preset
+	}
+	t, err := template.New("layout").Option("missingkey=zero").Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout: %w", err)
+	}
+	return &Layout{t: t}, nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+
+// Path returns the relative path for a container. Characters that are not
+// safe in file names, S3 keys and SSM parameter names are replaced by "_".
+func (l *Layout) Path(ctr inspector.Container) (string, error) {
+	var buf bytes.Buffer
+	if err := l.t.Execute(&buf, NewData(ctr)); err != nil {
+		return "", fmt.Errorf("failed to render layout for %s: %w", ctr.ShortID(), err)
+	}
+
+	p := SafeName(strings.TrimSpace(buf.String()))
+	if p == "" || p == "." {
+		return "", fmt.Errorf("layout produced an empty path for %s", ctr.ShortID())
+	}
+	return p, nil
+}
+
+// SafeName replaces characters that are not safe in file names, S3 keys
+// and SSM parameter names by "_", and trims leading and trailing slashes.
+func SafeName(s string) string {
+	return strings.Trim(path.Clean("/"+unsafeChars.ReplaceAllString(s, "_")), "/")
+}
+
+// Entry is one line of the index written next to the exported files.
+type Entry struct {
+	ID      string `yaml:"id" json:"id"`
+	Name    string `yaml:"name" json:"name"`
+	Image   string `yaml:"image" json:"image"`
+	Project string `yaml:"project,omitempty" json:"project,omitempty"`
+	Service string `yaml:"service,omitempty" json:"service,omitempty"`
+	Path    string `yaml:"path" json:"path"`
+}
+
+// Index maps short container IDs to where they were exported.
+type Index map[string]Entry
+
+// Add records the path of a container.
+func (idx Index) Add(ctr inspector.Container, p string) {
+	d := NewData(ctr)
+	idx[ctr.ShortID()] = Entry{
+		ID:      ctr.ID,
+		Name:    ctr.Name,
+		Image:   ctr.Image,
+		Project: d.Project,
+		Service: d.Service,
+		Path:    p,
+	}
+}
@@ -15,5 +15,5 @@ func init() {
 
 	logger.Formatter = &logrus.TextFormatter{}
 
-	log.SetLevel(log.InfoLevel)
+	logger.SetLevel(logrus.InfoLevel)
 }
@@ -0,0 +1,77 @@
+// Package nomad renders a container as the task stanza of a Nomad job
+// using the docker driver.
+package nomad
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/cmattoon/dockerenv/pkg/hcl"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/spec"
+)
+
+// Task returns the task stanza for the container described by s. Only
+// variables set on the container are included in its env block; labels
+// become task meta.
+func Task(s spec.Spec, vars []inspector.EnvVar) ([]byte, error) {
+	if s.Name == "" || s.Image == "" {
+		return nil, fmt.Errorf("container needs a name and an image")
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "task %s {\n", hcl.Quote(s.Name))
+	b.WriteString("  driver = \"docker\"\n")
+	if s.User != "" {
+		fmt.Fprintf(&b, "  user   = %s\n", hcl.Quote(s.User))
+	}
+
+	config := []hcl.Attr{{Name: "image", Value: hcl.Quote(s.Image)}}
+	if s.Entrypoint != nil {
+		config = append(config, hcl.Attr{Name: "entrypoint", Value: hcl.List(s.Entrypoint)})
+	}
+	if len(s.Cmd) > 0 {
+		config = append(config, hcl.Attr{Name: "command", Value: hcl.Quote(s.Cmd[0])})
+		if len(s.Cmd) > 1 {
+			config = append(config, hcl.Attr{Name: "args", Value: hcl.List(s.Cmd[1:])})
+		}
+	}
+	if s.WorkingDir != "" {
+		config = append(config, hcl.Attr{Name: "work_dir", Value: hcl.Quote(s.WorkingDir)})
+	}
+	b.WriteString("\n  config {\n")
+	if err := hcl.WriteAttrs(&b, "    ", config); err != nil {
+		return nil, err
+	}
+	b.WriteString("  }\n")
+
+	var env []hcl.Attr
+	for _, ev := range vars {
+		if ev.Source != inspector.SourceImage {
+			env = append(env, hcl.Attr{Name: ev.Name, Value: hcl.Quote(ev.Value)})
+		}
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+	if len(env) > 0 {
+		b.WriteString("\n  env {\n")
+		if err := hcl.WriteAttrs(&b, "    ", env); err != nil {
+			return nil, err
+		}
+		b.WriteString("  }\n")
+	}
+
+	var meta []hcl.Attr
+	for k, v := range s.Labels {
+		meta = append(meta, hcl.Attr{Name: k, Value: hcl.Quote(v)})
+	}
+	sort.Slice(meta, func(i, j int) bool { return meta[i].Name < meta[j].Name })
+	if len(meta) > 0 {
+		b.WriteString("\n  meta {\n")
+		if err := hcl.WriteAttrs(&b, "    ", meta); err != nil {
+			return nil, err
+		}
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
@@ -0,0 +1,43 @@
+// Package query applies JMESPath expressions to command results, the same
+// way the AWS CLI's --query option does.
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// Apply evaluates expr against doc. The document is converted to plain
+// JSON types first so that struct fields are addressed by their JSON names.
+func Apply(expr string, doc interface{}) (interface{}, error) {
+	jp, err := jmespath.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", expr, err)
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query document: %w", err)
+	}
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode query document: %w", err)
+	}
+
+	return jp.Search(data)
+}
+
+// Print writes a query result. Strings are written raw so they can be used
+// in shell scripts; everything else is written as indented JSON.
+func Print(w io.Writer, result interface{}) error {
+	if s, ok := result.(string); ok {
+		_, err := fmt.Fprintln(w, s)
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+//go:embed templates/report.html
+var htmlTemplate string
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(htmlTemplate))
+
+// HTML renders the report as a single HTML file with inline styles and no
+// external assets.
+func (r *Report) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var mdEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "`", "\\`", "*", `\*`, "_", `\_`, "<", "&lt;", ">", "&gt;")
+
+// md escapes s for a Markdown table cell.
+func md(s string) string {
+	return strings.ReplaceAll(mdEscaper.Replace(s), "\n", "<br>")
+}
+
+// Markdown renders the report as GitHub-flavored Markdown.
+func (r *Report) Markdown() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# dockerenv report\n\nGenerated %s\n", r.GeneratedAt.Format("2006-01-02 15:04:05 MST"))
+
+	for _, h := range r.Hosts {
+		fmt.Fprintf(&b, "\n## Host %s\n\n### Findings\n\n", md(h.Address))
+		if len(h.Findings) == 0 {
+			b.WriteString("No findings.\n")
+		} else {
+			b.WriteString("| Severity | Container | Variable | Finding |\n|---|---|---|---|\n")
+			for _, f := range h.Findings {
+				fmt.Fprintf(&b, "| **%s** | %s | %s | %s |\n", f.Severity, md(f.Container), md(f.Variable), md(f.Message))
+			}
+		}
+
+		b.WriteString("\n### Containers\n\n| Name | ID | Image | State | Variables | Certificates |\n|---|---|---|---|---|---|\n")
+		for _, c := range h.Containers {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %d | %d |\n", md(c.Name), c.ShortID(), md(c.Image), md(c.State), len(c.Vars), len(c.Certs))
+		}
+
+		for _, c := range h.Containers {
+			fmt.Fprintf(&b, "\n#### %s (%s, %s)\n\n| # | Variable | Source | Value |\n|---|---|---|---|\n", md(c.Name), c.ShortID(), md(c.Image))
+			for i, v := range c.Vars {
+				name := md(v.Name)
+				if v.Secret {
+					name += " (secret)"
+				}
+				fmt.Fprintf(&b, "| %d | %s | %s | %s |\n", i+1, name, v.Source, md(v.Value))
+			}
+			if len(c.Certs) > 0 {
+				b.WriteString("\n| Variable | Subject | Issuer | Not after | Status |\n|---|---|---|---|---|\n")
+				for _, cert := range c.Certs {
+					status := strings.ToUpper(cert.Status)
+					if cert.Status != "expired" {
+						status += fmt.Sprintf(" (%dd)", cert.DaysLeft)
+					}
+					fmt.Fprintf(&b, "| %s | %s | %s | %s | **%s** |\n", md(cert.Variable), md(cert.Subject), md(cert.Issuer), cert.NotAfter.Format("2006-01-02"), status)
+				}
+			}
+		}
+	}
+	return b.Bytes()
+}
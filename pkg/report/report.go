@@ -0,0 +1,184 @@
+// Package report builds a review document of one or more Docker hosts:
+// their containers, each container's environment in order with the
+// provenance of every variable, the certificates found in it, and
+// findings about how secrets and certificates are handled. It renders
+// as Markdown or as a single self-contained HTML file.
+package report
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cmattoon/dockerenv/pkg/certs"
+	"github.com/cmattoon/dockerenv/pkg/decode"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+// Options controls what the report shows.
+type Options struct {
+	Classifier *secrets.Classifier
+	// Policy is how secret values are shown.
+	Policy secrets.Policy
+	// WarnDays is how close to expiry a certificate is flagged.
+	WarnDays int
+	// Now is the time certificates are checked against.
+	Now time.Time
+}
+
+// Report is the whole document.
+type Report struct {
+	GeneratedAt time.Time
+	Hosts       []*Host
+}
+
+// Host is the part of the report about one daemon.
+type Host struct {
+	Address    string
+	Containers []*Container
+	Findings   []Finding
+}
+
+// Container is an inspected container with its annotated environment.
+type Container struct {
+	inspector.Container
+	Vars  []Var
+	Certs []Cert
+}
+
+// Var is a variable as shown in the report.
+type Var struct {
+	Name   string
+	Source string
+	Value  string
+	Secret bool
+}
+
+// Cert is a certificate found in a variable.
+type Cert struct {
+	certs.Info
+	Variable string
+	// Status is "expired", "expiring" or "valid".
+	Status   string
+	DaysLeft int
+}
+
+// Severity ranks findings.
+type Severity string
+
+const (
+	High   Severity = "high"
+	Medium Severity = "medium"
+	Low    Severity = "low"
+)
+
+var severityOrder = map[Severity]int{High: 0, Medium: 1, Low: 2}
+
+// Finding is a problem noticed in a container's environment.
+type Finding struct {
+	Severity  Severity
+	Container string
+	Variable  string
+	Message   string
+}
+
+// New returns an empty report.
+func New(opts Options) *Report {
+	return &Report{GeneratedAt: opts.Now}
+}
+
+// AddHost adds a host and its containers, checking them with opts.
+func (r *Report) AddHost(address string, containers []inspector.Container, opts Options) {
+	h := &Host{Address: address}
+	shared := map[[32]byte][]string{}
+
+	for _, ctr := range containers {
+		c := &Container{Container: ctr}
+		// Vars stands in for Env, whose values aren't masked.
+		c.Env = nil
+		for _, ev := range ctr.Env {
+			v := Var{Name: ev.Name, Source: ev.Source, Value: ev.Value}
+			// A private key is masked even when its name doesn't look
+			// secret, so that the report never prints what it flags.
+			hasKey := secrets.HoldsPrivateKey(ev.Value)
+			if hasKey || opts.Classifier != nil && opts.Classifier.IsSecret(ev.Name, ev.Value) {
+				v.Secret = true
+				v.Value = opts.Policy.Apply(ev.Value)
+				if ev.Value != "" {
+					sum := sha256.Sum256([]byte(ev.Value))
+					shared[sum] = append(shared[sum], ctr.Name+"/"+ev.Name)
+				}
+				if ev.Source == inspector.SourceImage {
+					h.finding(Medium, ctr.Name, ev.Name, "secret is baked into the image")
+				}
+				if ev.Value == "" {
+					h.finding(Low, ctr.Name, ev.Name, "secret variable is empty")
+				}
+			}
+			switch {
+			case !hasKey:
+			case strings.Contains(ev.Value, "PRIVATE KEY-----"):
+				h.finding(High, ctr.Name, ev.Name, "private key stored in the environment")
+			default:
+				_, chain := decode.Auto(ev.Value)
+				h.finding(High, ctr.Name, ev.Name, fmt.Sprintf("private key stored in the environment (decoded: %s)", chain))
+			}
+			c.Vars = append(c.Vars, v)
+			c.Certs = append(c.Certs, findCerts(ev, opts)...)
+		}
+		for _, cert := range c.Certs {
+			switch cert.Status {
+			case "expired":
+				h.finding(High, ctr.Name, cert.Variable, fmt.Sprintf("certificate %s expired on %s", cert.Subject, cert.NotAfter.Format("2006-01-02")))
+			case "expiring":
+				h.finding(Medium, ctr.Name, cert.Variable, fmt.Sprintf("certificate %s expires in %d days", cert.Subject, cert.DaysLeft))
+			}
+		}
+		h.Containers = append(h.Containers, c)
+	}
+
+	for _, uses := range shared {
+		if len(uses) > 1 {
+			sort.Strings(uses)
+			h.finding(Low, "", "", "the same secret value is used by "+strings.Join(uses, ", "))
+		}
+	}
+	sort.SliceStable(h.Findings, func(i, j int) bool {
+		a, b := h.Findings[i], h.Findings[j]
+		if a.Severity != b.Severity {
+			return severityOrder[a.Severity] < severityOrder[b.Severity]
+		}
+		if a.Container != b.Container {
+			return a.Container < b.Container
+		}
+		return a.Message < b.Message
+	})
+	r.Hosts = append(r.Hosts, h)
+}
+
+func (h *Host) finding(sev Severity, container, variable, msg string) {
+	h.Findings = append(h.Findings, Finding{Severity: sev, Container: container, Variable: variable, Message: msg})
+}
+
+// findCerts returns the certificates in ev, decoding the value first if
+// it is encoded.
+func findCerts(ev inspector.EnvVar, opts Options) []Cert {
+	decoded, _ := decode.Auto(ev.Value)
+	infos, _ := certs.ParsePEM([]byte(decoded))
+	var found []Cert
+	for _, info := range infos {
+		c := Cert{Info: info, Variable: ev.Name, Status: "valid"}
+		c.DaysLeft = int(info.NotAfter.Sub(opts.Now).Hours() / 24)
+		switch {
+		case info.Expired(opts.Now):
+			c.Status = "expired"
+		case c.DaysLeft < opts.WarnDays:
+			c.Status = "expiring"
+		}
+		found = append(found, c)
+	}
+	return found
+}
@@ -0,0 +1,361 @@
+// Package runcmd renders a container spec as a `docker run` command.
+package runcmd
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/cmattoon/dockerenv/pkg/dotenv"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+	"github.com/cmattoon/dockerenv/pkg/shell"
+	"github.com/cmattoon/dockerenv/pkg/spec"
+)
+
+// Options controls how the command is written.
+type Options struct {
+	// Classifier picks the variables replaced by placeholders. Values are
+	// never hidden when it is nil.
+	Classifier *secrets.Classifier
+	// EnvFile, when set, moves the environment into this file, referenced
+	// with --env-file. The caller writes Command.EnvFile to it.
+	EnvFile string
+}
+
+// Command is a generated `docker run` invocation.
+type Command struct {
+	// Lines holds "docker run" followed by one option (with its value) per
+	// line, then the image and its arguments.
+	Lines [][]string
+	// EnvFile is the content of Options.EnvFile, in the docker dialect.
+	EnvFile []byte
+	// Placeholders lists the secret variables that are passed through from
+	// the caller's environment instead of being written out.
+	Placeholders []string
+	// Unsupported describes settings that the command doesn't reproduce.
+	Unsupported []string
+}
+
+// Generate builds the command for raw, whose creation-time settings are s.
+func Generate(raw types.ContainerJSON, s spec.Spec, opts Options) (*Command, error) {
+	if s.Image == "" {
+		return nil, fmt.Errorf("container %s has no image", s.Name)
+	}
+	cmd := &Command{Lines: [][]string{{"docker", "run", "-d"}}}
+	add := func(words ...string) {
+		cmd.Lines = append(cmd.Lines, words)
+	}
+
+	if s.Name != "" {
+		add("--name", s.Name)
+	}
+	if s.Hostname != "" {
+		add("--hostname", s.Hostname)
+	}
+	if s.User != "" {
+		add("--user", s.User)
+	}
+	if s.WorkingDir != "" {
+		add("--workdir", s.WorkingDir)
+	}
+	cmd.env(s.Env, opts)
+	for _, k := range sortedKeys(s.Labels) {
+		add("--label", k+"="+s.Labels[k])
+	}
+
+	for _, p := range s.Ports {
+		add("--publish", p.String())
+	}
+	for _, m := range s.Mounts {
+		switch m.Type {
+		case "tmpfs":
+			add("--tmpfs", m.Target)
+		default:
+			v := m.Source + ":" + m.Target
+			if m.ReadOnly {
+				v += ":ro"
+			}
+			add("--volume", v)
+		}
+	}
+
+	switch {
+	case s.NetworkMode != "":
+		add("--network", s.NetworkMode)
+	case len(s.Networks) > 0:
+		add("--network", s.Networks[0])
+		for _, n := range s.Networks[1:] {
+			cmd.Unsupported = append(cmd.Unsupported, fmt.Sprintf("network %s: attach it with `docker network connect %s %s`", n, n, s.Name))
+		}
+	}
+	for _, h := range s.ExtraHosts {
+		add("--add-host", h)
+	}
+	for _, d := range s.DNS {
+		add("--dns", d)
+	}
+	if s.Restart != "" {
+		add("--restart", s.Restart)
+	}
+
+	for _, c := range s.CapAdd {
+		add("--cap-add", c)
+	}
+	for _, c := range s.CapDrop {
+		add("--cap-drop", c)
+	}
+	if s.Privileged {
+		add("--privileged")
+	}
+	if s.ReadOnly {
+		add("--read-only")
+	}
+	for _, o := range s.SecurityOpt {
+		add("--security-opt", o)
+	}
+	for _, d := range s.Devices {
+		add("--device", d)
+	}
+
+	r := s.Resources
+	if r.Memory > 0 {
+		add("--memory", bytesize(r.Memory))
+	}
+	if r.MemoryReservation > 0 {
+		add("--memory-reservation", bytesize(r.MemoryReservation))
+	}
+	if r.MemorySwap != 0 {
+		if r.MemorySwap < 0 {
+			add("--memory-swap", "-1")
+		} else {
+			add("--memory-swap", bytesize(r.MemorySwap))
+		}
+	}
+	if r.NanoCPUs > 0 {
+		add("--cpus", strconv.FormatFloat(float64(r.NanoCPUs)/1e9, 'f', -1, 64))
+	}
+	if r.CPUShares > 0 {
+		add("--cpu-shares", strconv.FormatInt(r.CPUShares, 10))
+	}
+	if r.PidsLimit > 0 {
+		add("--pids-limit", strconv.FormatInt(r.PidsLimit, 10))
+	}
+	if r.ShmSize > 0 {
+		add("--shm-size", bytesize(r.ShmSize))
+	}
+
+	if s.LogDriver != "" {
+		add("--log-driver", s.LogDriver)
+		for _, k := range sortedKeys(s.LogOptions) {
+			add("--log-opt", k+"="+s.LogOptions[k])
+		}
+	}
+
+	if hc := s.Healthcheck; hc != nil {
+		switch hc.Test[0] {
+		case "NONE":
+			add("--no-healthcheck")
+		case "CMD-SHELL":
+			add("--health-cmd", strings.Join(hc.Test[1:], " "))
+		case "CMD":
+			add("--health-cmd", shell.Join(hc.Test[1:]))
+			cmd.Unsupported = append(cmd.Unsupported, "healthcheck: exec form is run through a shell by --health-cmd")
+		}
+		for _, d := range []struct {
+			flag string
+			d    time.Duration
+		}{{"--health-interval", hc.Interval}, {"--health-timeout", hc.Timeout}, {"--health-start-period", hc.StartPeriod}} {
+			if d.d > 0 {
+				add(d.flag, d.d.String())
+			}
+		}
+		if hc.Retries > 0 {
+			add("--health-retries", strconv.Itoa(hc.Retries))
+		}
+	}
+
+	// --entrypoint takes a single word; the rest goes in front of the command
+	args := s.Cmd
+	if s.Entrypoint != nil {
+		if len(s.Entrypoint) == 0 {
+			add("--entrypoint", "")
+		} else {
+			add("--entrypoint", s.Entrypoint[0])
+			args = append(append([]string{}, s.Entrypoint[1:]...), s.Cmd...)
+			if s.Cmd == nil && raw.Config != nil && len(raw.Config.Cmd) > 0 {
+				// --entrypoint resets the image command, so spell it out
+				args = append(args, raw.Config.Cmd...)
+			}
+		}
+	}
+	if raw.HostConfig != nil && raw.HostConfig.AutoRemove {
+		cmd.Lines[0] = append(cmd.Lines[0], "--rm")
+	}
+	add(append([]string{s.Image}, args...)...)
+
+	cmd.Unsupported = append(cmd.Unsupported, unsupported(raw)...)
+	return cmd, nil
+}
+
+// env adds the environment to the command line or the env file. Secrets
+// become bare names, which docker fills in from the caller's environment.
+func (cmd *Command) env(vars []inspector.EnvVar, opts Options) {
+	var file bytes.Buffer
+	for _, ev := range vars {
+		hidden := opts.Classifier != nil && opts.Classifier.IsSecret(ev.Name, ev.Value)
+		if hidden {
+			cmd.Placeholders = append(cmd.Placeholders, ev.Name)
+		}
+		if opts.EnvFile != "" {
+			if hidden {
+				file.WriteString(ev.Name + "\n")
+				continue
+			}
+			line, err := dotenv.Marshal([]inspector.EnvVar{ev}, dotenv.Docker)
+			if err == nil {
+				file.Write(line)
+				continue
+			}
+			// multi-line values can't go in a docker env file
+		}
+		if hidden {
+			cmd.Lines = append(cmd.Lines, []string{"--env", ev.Name})
+		} else {
+			cmd.Lines = append(cmd.Lines, []string{"--env", ev.Name + "=" + ev.Value})
+		}
+	}
+	if opts.EnvFile != "" {
+		cmd.EnvFile = file.Bytes()
+		cmd.Lines = append(cmd.Lines, []string{"--env-file", opts.EnvFile})
+	}
+}
+
+// unsupported lists settings of raw that Generate doesn't carry over.
+func unsupported(raw types.ContainerJSON) []string {
+	var notes []string
+	note := func(format string, args ...interface{}) {
+		notes = append(notes, fmt.Sprintf(format, args...))
+	}
+
+	if raw.Config != nil && strings.HasPrefix(raw.Config.Image, "sha256:") {
+		note("image: the container was created from an untagged image ID")
+	}
+	for _, m := range raw.Mounts {
+		if m.Type == "volume" && m.Driver != "" && m.Driver != "local" {
+			note("volume %s: driver %s and its options", m.Name, m.Driver)
+		}
+		if m.Type == "bind" && m.Propagation != "" && m.Propagation != "rprivate" {
+			note("bind mount %s: %s propagation", m.Destination, m.Propagation)
+		}
+		if m.Type != "bind" && m.Type != "volume" && m.Type != "tmpfs" {
+			note("mount %s: type %s", m.Destination, m.Type)
+		}
+	}
+	if raw.NetworkSettings != nil {
+		var names []string
+		for name := range raw.NetworkSettings.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			ep := raw.NetworkSettings.Networks[name]
+			if ep == nil {
+				continue
+			}
+			if ep.IPAMConfig != nil && (ep.IPAMConfig.IPv4Address != "" || ep.IPAMConfig.IPv6Address != "") {
+				note("network %s: static address", name)
+			}
+			if len(ep.Links) > 0 {
+				note("network %s: links %s", name, strings.Join(ep.Links, ", "))
+			}
+		}
+	}
+
+	hc := raw.HostConfig
+	if hc == nil {
+		return notes
+	}
+	checks := []struct {
+		what string
+		set  bool
+	}{
+		{"links", len(hc.Links) > 0},
+		{"volumes-from", len(hc.VolumesFrom) > 0},
+		{"sysctls", len(hc.Sysctls) > 0},
+		{"ulimits", len(hc.Ulimits) > 0},
+		{"GPU and other device requests", len(hc.DeviceRequests) > 0},
+		{"device cgroup rules", len(hc.DeviceCgroupRules) > 0},
+		{"runtime " + hc.Runtime, hc.Runtime != "" && hc.Runtime != "runc"},
+		{"pid mode " + string(hc.PidMode), hc.PidMode != ""},
+		{"uts mode " + string(hc.UTSMode), hc.UTSMode != ""},
+		{"ipc mode " + string(hc.IpcMode), hc.IpcMode.IsContainer() || hc.IpcMode.IsHost()},
+		{"userns mode " + string(hc.UsernsMode), hc.UsernsMode != ""},
+		{"cgroup parent", hc.CgroupParent != ""},
+		{"cpu period and quota", hc.CPUPeriod > 0 || hc.CPUQuota > 0},
+		{"cpuset", hc.CpusetCpus != "" || hc.CpusetMems != ""},
+		{"block IO limits", hc.BlkioWeight > 0 || len(hc.BlkioDeviceReadBps) > 0 || len(hc.BlkioDeviceWriteBps) > 0},
+		{"OOM settings", hc.OomKillDisable != nil && *hc.OomKillDisable || hc.OomScoreAdj != 0},
+		{"additional groups", len(hc.GroupAdd) > 0},
+		{"DNS search domains and options", len(hc.DNSSearch) > 0 || len(hc.DNSOptions) > 0},
+		{"storage options", len(hc.StorageOpt) > 0},
+		{"init process", hc.Init != nil && *hc.Init},
+		{"publish all ports", hc.PublishAllPorts},
+	}
+	for _, c := range checks {
+		if c.set {
+			note("%s", c.what)
+		}
+	}
+	return notes
+}
+
+// String renders the command for a POSIX shell, one option per line,
+// preceded by comments listing placeholders and unsupported settings.
+func (cmd *Command) String() string {
+	var b strings.Builder
+	if len(cmd.Unsupported) > 0 {
+		b.WriteString("# not reproduced:\n")
+		for _, u := range cmd.Unsupported {
+			b.WriteString("#   - " + u + "\n")
+		}
+	}
+	if len(cmd.Placeholders) > 0 {
+		b.WriteString("# secrets are read from your environment: " + strings.Join(cmd.Placeholders, ", ") + "\n")
+	}
+	for i, line := range cmd.Lines {
+		if i > 0 {
+			b.WriteString(" \\\n  ")
+		}
+		b.WriteString(shell.Join(line))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// bytesize formats n with the largest unit docker accepts that divides it.
+func bytesize(n int64) string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}} {
+		if n%u.size == 0 {
+			return strconv.FormatInt(n/u.size, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
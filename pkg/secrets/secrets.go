@@ -0,0 +1,152 @@
+// Package secrets contains helpers for displaying values without leaking them.
+package secrets
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cmattoon/dockerenv/pkg/decode"
+)
+
+// DefaultPatterns match variable names that usually hold credentials.
+var DefaultPatterns = []string{`(?i)(PASS|SECRET|TOKEN|PRIVATE|CREDENTIAL|API_?KEY|ACCESS_?KEY|_KEY$|^KEY$|DSN|AUTH)`}
+
+// Classifier decides which variables are secret.
+type Classifier struct {
+	patterns []*regexp.Regexp
+}
+
+// Default is the classifier built from DefaultPatterns.
+var Default, _ = NewClassifier()
+
+// NewClassifier returns a classifier for the given name patterns (regular
+// expressions). With no patterns, DefaultPatterns are used.
+func NewClassifier(patterns ...string) (*Classifier, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+	c := &Classifier{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern '%s': %w", p, err)
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	return c, nil
+}
+
+// IsSecret reports whether a variable is secret, judging by its name and
+// by whether the value holds a private key, as is or once decoded.
+func (c *Classifier) IsSecret(name, value string) bool {
+	if HoldsPrivateKey(value) {
+		return true
+	}
+	for _, re := range c.patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// HoldsPrivateKey reports whether value is a PEM private key, or becomes
+// one when its layers of encoding are undone (see decode.Auto).
+func HoldsPrivateKey(value string) bool {
+	if strings.Contains(value, "PRIVATE KEY-----") {
+		return true
+	}
+	decoded, chain := decode.Auto(value)
+	return len(chain) > 0 && strings.Contains(decoded, "PRIVATE KEY-----")
+}
+
+// LooksSecret reports whether a variable should be hidden by default.
+func LooksSecret(name, value string) bool {
+	return Default.IsSecret(name, value)
+}
+
+// Mask hides a value. Long values keep their last four characters so
+// that two masked values can be told apart at a glance.
+func Mask(value string) string {
+	n := utf8.RuneCountInString(value)
+	if n == 0 {
+		return ""
+	}
+	if n < 12 {
+		return strings.Repeat("*", 8)
+	}
+	r := []rune(value)
+	return strings.Repeat("*", 8) + string(r[n-4:])
+}
+
+// Hash returns the hex-encoded SHA-256 digest of a value, which is what
+// `printf %s "$value" | sha256sum` prints.
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// FingerprintKeyEnv names the variable holding the key of Fingerprint.
+const FingerprintKeyEnv = "DOCKERENV_HASH_KEY"
+
+var (
+	fingerprintKey  []byte
+	fingerprintOnce sync.Once
+)
+
+// Fingerprint returns the first 16 hex digits of the HMAC-SHA256 of a
+// value. Unlike Hash it can't be reversed by hashing candidate passwords
+// offline. The key comes from $DOCKERENV_HASH_KEY, so fingerprints can be
+// compared across runs, and is otherwise random, so they only compare
+// within one run.
+func Fingerprint(value string) string {
+	fingerprintOnce.Do(func() {
+		fingerprintKey = []byte(os.Getenv(FingerprintKeyEnv))
+		if len(fingerprintKey) == 0 {
+			fingerprintKey = make([]byte, 32)
+			if _, err := rand.Read(fingerprintKey); err != nil {
+				panic(fmt.Sprintf("secrets: no randomness for the fingerprint key: %s", err))
+			}
+		}
+	})
+	mac := hmac.New(sha256.New, fingerprintKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Policy says how secret values are shown in reports and exports.
+type Policy string
+
+const (
+	PolicyMask   Policy = "mask"
+	PolicyHash   Policy = "hash"
+	PolicyReveal Policy = "reveal"
+)
+
+// ParsePolicy returns the policy with the given name.
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(name); p {
+	case PolicyMask, PolicyHash, PolicyReveal:
+		return p, nil
+	}
+	return PolicyMask, fmt.Errorf("unknown value policy '%s' (valid: mask, hash, reveal)", name)
+}
+
+// Apply returns value as shown under the policy.
+func (p Policy) Apply(value string) string {
+	switch p {
+	case PolicyReveal:
+		return value
+	case PolicyHash:
+		return Fingerprint(value)
+	}
+	return Mask(value)
+}
@@ -0,0 +1,111 @@
+// Package shell quotes words and writes environment assignments for
+// POSIX shells, fish and PowerShell.
+package shell
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var safe = regexp.MustCompile(`^[A-Za-z0-9_./:@,+%=^-]+$`)
+
+// Quote returns s as a single word for sh, bash and zsh. Words made only
+// of safe characters are returned unchanged.
+func Quote(s string) string {
+	if safe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Join quotes each word and joins them with spaces.
+func Join(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = Quote(w)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Shell is a shell dialect for environment statements.
+type Shell string
+
+const (
+	Bash       Shell = "bash"
+	Zsh        Shell = "zsh"
+	Fish       Shell = "fish"
+	PowerShell Shell = "powershell"
+)
+
+// Parse returns the shell with the given name. "sh" and "pwsh" are
+// accepted as aliases.
+func Parse(name string) (Shell, error) {
+	switch name {
+	case "bash", "sh":
+		return Bash, nil
+	case "zsh":
+		return Zsh, nil
+	case "fish":
+		return Fish, nil
+	case "powershell", "pwsh":
+		return PowerShell, nil
+	}
+	return Bash, fmt.Errorf("unknown shell '%s' (valid: bash, zsh, fish, powershell)", name)
+}
+
+var posixName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidName reports whether the shell can set a variable called name.
+// PowerShell can set any name; the others need an identifier.
+func (sh Shell) ValidName(name string) bool {
+	if sh == PowerShell {
+		return name != "" && !strings.ContainsAny(name, "=\x00")
+	}
+	return posixName.MatchString(name)
+}
+
+// Export returns the statement that sets and exports name to value.
+func (sh Shell) Export(name, value string) string {
+	switch sh {
+	case Fish:
+		return "set -gx " + name + " " + quoteFish(value) + ";"
+	case PowerShell:
+		return psEnv(name) + " = " + quotePS(value)
+	}
+	return "export " + name + "=" + Quote(value) + ";"
+}
+
+// Unset returns the statement that removes name from the environment.
+func (sh Shell) Unset(name string) string {
+	switch sh {
+	case Fish:
+		return "set -e " + name + ";"
+	case PowerShell:
+		return "Remove-Item " + quotePS("Env:"+name) + " -ErrorAction SilentlyContinue"
+	}
+	return "unset " + name + ";"
+}
+
+// quoteFish single quotes s; inside them fish only treats \\ and \' as
+// escapes.
+func quoteFish(s string) string {
+	if safe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// quotePS single quotes s; PowerShell doubles quotes to escape them.
+func quotePS(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// psEnv returns the variable expression for an environment variable,
+// using braces when the name isn't a plain identifier.
+func psEnv(name string) string {
+	if posixName.MatchString(name) {
+		return "$env:" + name
+	}
+	return "${env:" + strings.NewReplacer("`", "``", "}", "`}", "{", "`{").Replace(name) + "}"
+}
@@ -0,0 +1,278 @@
+// Package spec reduces a container's full inspection to the settings that
+// were chosen when it was created, leaving out anything inherited from
+// its image or filled in by the daemon. Generators such as compose build
+// on it.
+package spec
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+// Spec is the creation-time configuration of a container.
+type Spec struct {
+	Name        string
+	Image       string
+	Entrypoint  []string
+	Cmd         []string
+	Env         []inspector.EnvVar
+	Labels      map[string]string
+	Ports       []Port
+	Mounts      []Mount
+	NetworkMode string
+	Networks    []string
+	Restart     string
+	Healthcheck *container.HealthConfig
+	User        string
+	WorkingDir  string
+	Hostname    string
+
+	CapAdd      []string
+	CapDrop     []string
+	Privileged  bool
+	ReadOnly    bool
+	SecurityOpt []string
+	DNS         []string
+	ExtraHosts  []string
+	Devices     []string
+	Resources   Resources
+	LogDriver   string
+	LogOptions  map[string]string
+}
+
+// Resources holds the limits set on a container. Zero means unset.
+type Resources struct {
+	Memory            int64
+	MemoryReservation int64
+	MemorySwap        int64
+	NanoCPUs          int64
+	CPUShares         int64
+	PidsLimit         int64
+	ShmSize           int64
+}
+
+// defaultShmSize is the /dev/shm size the daemon uses unless told otherwise.
+const defaultShmSize = 64 * 1024 * 1024
+
+// Port is a published port.
+type Port struct {
+	HostIP        string
+	HostPort      string
+	ContainerPort string
+	Protocol      string
+}
+
+// String formats p in the short syntax shared by compose and docker run,
+// [HOST_IP:][HOST_PORT:]CONTAINER_PORT[/PROTOCOL].
+func (p Port) String() string {
+	s := p.ContainerPort
+	if p.Protocol != "" && p.Protocol != "tcp" {
+		s += "/" + p.Protocol
+	}
+	if p.HostPort != "" || p.HostIP != "" {
+		s = p.HostPort + ":" + s
+	}
+	if p.HostIP != "" {
+		host := p.HostIP
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		s = host + ":" + s
+	}
+	return s
+}
+
+// Mount is a bind mount, named volume or tmpfs.
+type Mount struct {
+	Type     string
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+// FromContainer builds the spec of ctr. Settings equal to those of img are
+// left out; img may be nil if the image is gone.
+func FromContainer(ctr types.ContainerJSON, img *types.ImageInspect) Spec {
+	s := Spec{
+		Name:   strings.TrimPrefix(ctr.Name, "/"),
+		Labels: map[string]string{},
+	}
+	imgConfig := &container.Config{}
+	if img != nil && img.Config != nil {
+		imgConfig = img.Config
+	}
+
+	if cfg := ctr.Config; cfg != nil {
+		s.Image = cfg.Image
+		if !reflect.DeepEqual([]string(cfg.Entrypoint), []string(imgConfig.Entrypoint)) {
+			s.Entrypoint = cfg.Entrypoint
+		}
+		if !reflect.DeepEqual([]string(cfg.Cmd), []string(imgConfig.Cmd)) {
+			s.Cmd = cfg.Cmd
+		}
+
+		inherited := map[string]bool{}
+		for _, kv := range imgConfig.Env {
+			inherited[kv] = true
+		}
+		for _, kv := range cfg.Env {
+			if inherited[kv] {
+				continue
+			}
+			x := strings.SplitN(kv, "=", 2)
+			ev := inspector.EnvVar{Name: x[0], Source: inspector.SourceContainer}
+			if len(x) == 2 {
+				ev.Value = x[1]
+			}
+			s.Env = append(s.Env, ev)
+		}
+
+		for k, v := range cfg.Labels {
+			if iv, ok := imgConfig.Labels[k]; ok && iv == v {
+				continue
+			}
+			if strings.HasPrefix(k, "com.docker.compose.") {
+				continue
+			}
+			s.Labels[k] = v
+		}
+
+		if cfg.User != imgConfig.User {
+			s.User = cfg.User
+		}
+		if cfg.WorkingDir != imgConfig.WorkingDir {
+			s.WorkingDir = cfg.WorkingDir
+		}
+		// the daemon names the host after the container ID unless told otherwise
+		if cfg.Hostname != "" && !strings.HasPrefix(ctr.ID, cfg.Hostname) {
+			s.Hostname = cfg.Hostname
+		}
+		if cfg.Healthcheck != nil && len(cfg.Healthcheck.Test) > 0 &&
+			!reflect.DeepEqual(cfg.Healthcheck, imgConfig.Healthcheck) {
+			s.Healthcheck = cfg.Healthcheck
+		}
+	}
+
+	if hc := ctr.HostConfig; hc != nil {
+		for port, bindings := range hc.PortBindings {
+			for _, b := range bindings {
+				s.Ports = append(s.Ports, Port{
+					HostIP:        b.HostIP,
+					HostPort:      b.HostPort,
+					ContainerPort: port.Port(),
+					Protocol:      port.Proto(),
+				})
+			}
+		}
+		sort.Slice(s.Ports, func(i, j int) bool {
+			if s.Ports[i].ContainerPort != s.Ports[j].ContainerPort {
+				return s.Ports[i].ContainerPort < s.Ports[j].ContainerPort
+			}
+			return s.Ports[i].HostPort < s.Ports[j].HostPort
+		})
+
+		switch p := hc.RestartPolicy; p.Name {
+		case "", "no":
+		case "on-failure":
+			s.Restart = "on-failure"
+			if p.MaximumRetryCount > 0 {
+				s.Restart += ":" + strconv.Itoa(p.MaximumRetryCount)
+			}
+		default:
+			s.Restart = p.Name
+		}
+
+		switch mode := string(hc.NetworkMode); {
+		case mode == "host", mode == "none", strings.HasPrefix(mode, "container:"):
+			s.NetworkMode = mode
+		}
+
+		for target := range hc.Tmpfs {
+			s.Mounts = append(s.Mounts, Mount{Type: "tmpfs", Target: target})
+		}
+
+		s.CapAdd = hc.CapAdd
+		s.CapDrop = hc.CapDrop
+		s.Privileged = hc.Privileged
+		s.ReadOnly = hc.ReadonlyRootfs
+		s.SecurityOpt = hc.SecurityOpt
+		s.DNS = hc.DNS
+		s.ExtraHosts = hc.ExtraHosts
+		for _, d := range hc.Devices {
+			dev := d.PathOnHost + ":" + d.PathInContainer
+			if d.CgroupPermissions != "" && d.CgroupPermissions != "rwm" {
+				dev += ":" + d.CgroupPermissions
+			}
+			s.Devices = append(s.Devices, dev)
+		}
+
+		s.Resources = Resources{
+			Memory:            hc.Memory,
+			MemoryReservation: hc.MemoryReservation,
+			MemorySwap:        hc.MemorySwap,
+			NanoCPUs:          hc.NanoCPUs,
+			CPUShares:         hc.CPUShares,
+		}
+		if hc.PidsLimit != nil && *hc.PidsLimit > 0 {
+			s.Resources.PidsLimit = *hc.PidsLimit
+		}
+		if hc.ShmSize != defaultShmSize {
+			s.Resources.ShmSize = hc.ShmSize
+		}
+		// memory-swap defaults to twice the memory limit
+		if s.Resources.MemorySwap == 2*s.Resources.Memory {
+			s.Resources.MemorySwap = 0
+		}
+
+		// json-file is the stock default; a daemon configured otherwise
+		// reports its own default, which we can't tell from a choice
+		if lc := hc.LogConfig; lc.Type != "json-file" || len(lc.Config) > 0 {
+			s.LogDriver = lc.Type
+			s.LogOptions = lc.Config
+		}
+	}
+
+	for _, m := range ctr.Mounts {
+		switch m.Type {
+		case "bind":
+			s.Mounts = append(s.Mounts, Mount{Type: "bind", Source: m.Source, Target: m.Destination, ReadOnly: !m.RW})
+		case "volume":
+			// anonymous volumes created for an image VOLUME come back on their own
+			if _, declared := imgConfig.Volumes[m.Destination]; declared && isAnonymous(m.Name) {
+				continue
+			}
+			s.Mounts = append(s.Mounts, Mount{Type: "volume", Source: m.Name, Target: m.Destination, ReadOnly: !m.RW})
+		}
+	}
+	sort.Slice(s.Mounts, func(i, j int) bool { return s.Mounts[i].Target < s.Mounts[j].Target })
+
+	if s.NetworkMode == "" && ctr.NetworkSettings != nil {
+		for name := range ctr.NetworkSettings.Networks {
+			if name != "bridge" {
+				s.Networks = append(s.Networks, name)
+			}
+		}
+		sort.Strings(s.Networks)
+	}
+	return s
+}
+
+// isAnonymous reports whether a volume name was generated by the daemon.
+func isAnonymous(name string) bool {
+	if len(name) != 64 {
+		return false
+	}
+	for _, r := range name {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
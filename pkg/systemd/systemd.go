@@ -0,0 +1,270 @@
+// Package systemd writes a container's environment for systemd: as an
+// EnvironmentFile, as Environment= lines, and as the unit files of a
+// service that runs the same program natively or through podman.
+//
+// The two environment syntaxes differ. EnvironmentFile= values may be
+// double quoted, where \", \\, \$ and \` are escapes and newlines are kept
+// literally. Environment= lines are parsed like the rest of a unit file:
+// C escapes such as \n are understood and % starts a specifier, so it is
+// written as %%. In Exec lines $ also starts a variable and is written $$.
+package systemd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/spec"
+)
+
+var (
+	validName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	plain     = regexp.MustCompile(`^[A-Za-z0-9_./:@,+=^-]*$`)
+	unitChars = regexp.MustCompile(`[^A-Za-z0-9:_.\\-]+`)
+)
+
+// UnitName converts s to a valid unit name prefix.
+func UnitName(s string) string {
+	return strings.Trim(unitChars.ReplaceAllString(strings.TrimPrefix(s, "/"), "-"), "-")
+}
+
+// Split separates vars with names systemd accepts from the rest, which
+// are returned by name.
+func Split(vars []inspector.EnvVar) (valid []inspector.EnvVar, skipped []string) {
+	for _, ev := range vars {
+		if validName.MatchString(ev.Name) {
+			valid = append(valid, ev)
+		} else {
+			skipped = append(skipped, ev.Name)
+		}
+	}
+	return valid, skipped
+}
+
+// EnvironmentFile encodes vars in EnvironmentFile= syntax, one per line.
+// Names systemd doesn't accept are an error; see Split.
+func EnvironmentFile(vars []inspector.EnvVar) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, ev := range vars {
+		if !validName.MatchString(ev.Name) {
+			return nil, fmt.Errorf("%s: invalid variable name for systemd", ev.Name)
+		}
+		buf.WriteString(ev.Name + "=" + QuoteEnvironmentFile(ev.Value) + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// QuoteEnvironmentFile returns value as written in an EnvironmentFile.
+func QuoteEnvironmentFile(value string) string {
+	if plain.MatchString(value) {
+		return value
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		if strings.ContainsRune("\"\\$`", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Environment returns an Environment= line setting ev.
+func Environment(ev inspector.EnvVar) string {
+	return "Environment=" + quoteUnit(ev.Name+"="+ev.Value, false)
+}
+
+// quoteUnit double quotes s with C escapes and escapes specifiers. In Exec
+// lines (exec) $ is escaped as well.
+func quoteUnit(s string, exec bool) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '%':
+			b.WriteString("%%")
+		case '$':
+			if exec {
+				b.WriteString("$$")
+			} else {
+				b.WriteRune(r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Exec returns words as the command line of an Exec setting.
+func Exec(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if plain.MatchString(w) && w != "" {
+			quoted[i] = w
+		} else {
+			quoted[i] = quoteUnit(w, true)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Options controls the generated unit.
+type Options struct {
+	// Description of the unit; the container name if empty.
+	Description string
+	// EnvironmentFile is the path the unit reads the environment from. The
+	// environment is written inline with Environment= lines when empty.
+	EnvironmentFile string
+	// Podman is the podman binary used by PodmanUnit.
+	Podman string
+}
+
+// ServiceUnit returns a skeleton unit that runs the container's program
+// directly, with its environment, user and working directory.
+func ServiceUnit(s spec.Spec, argv []string, vars []inspector.EnvVar, opts Options) []byte {
+	var b bytes.Buffer
+	writeUnitSection(&b, s, opts, false)
+
+	b.WriteString("[Service]\nType=simple\n")
+	writeEnvironment(&b, vars, opts)
+	if s.User != "" {
+		user := strings.SplitN(s.User, ":", 2)
+		fmt.Fprintf(&b, "User=%s\n", user[0])
+		if len(user) == 2 {
+			fmt.Fprintf(&b, "Group=%s\n", user[1])
+		}
+	}
+	if s.WorkingDir != "" {
+		fmt.Fprintf(&b, "WorkingDirectory=%s\n", s.WorkingDir)
+	}
+	if len(argv) == 0 {
+		argv = []string{"/bin/false"}
+		b.WriteString("# the container had no command; replace ExecStart\n")
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", Exec(argv))
+	fmt.Fprintf(&b, "Restart=%s\n", restart(s.Restart))
+	b.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	return b.Bytes()
+}
+
+// PodmanUnit returns a unit in the style of `podman generate systemd
+// --new`, which runs the container's image with podman. The environment
+// is loaded into the unit and passed through by name, so multi-line
+// values survive.
+func PodmanUnit(s spec.Spec, vars []inspector.EnvVar, opts Options) []byte {
+	podman := opts.Podman
+	if podman == "" {
+		podman = "/usr/bin/podman"
+	}
+
+	run := []string{"--name", s.Name}
+	for _, ev := range vars {
+		run = append(run, "--env", ev.Name)
+	}
+	for _, p := range s.Ports {
+		run = append(run, "--publish", p.String())
+	}
+	for _, m := range s.Mounts {
+		if m.Type == "tmpfs" {
+			run = append(run, "--tmpfs", m.Target)
+			continue
+		}
+		v := m.Source + ":" + m.Target
+		if m.ReadOnly {
+			v += ":ro"
+		}
+		run = append(run, "--volume", v)
+	}
+	if s.NetworkMode != "" {
+		run = append(run, "--network", s.NetworkMode)
+	} else if len(s.Networks) > 0 {
+		run = append(run, "--network", strings.Join(s.Networks, ","))
+	}
+	if s.User != "" {
+		run = append(run, "--user", s.User)
+	}
+	if s.WorkingDir != "" {
+		run = append(run, "--workdir", s.WorkingDir)
+	}
+	for _, c := range s.CapAdd {
+		run = append(run, "--cap-add", c)
+	}
+	for _, c := range s.CapDrop {
+		run = append(run, "--cap-drop", c)
+	}
+	if s.Entrypoint != nil {
+		// podman takes a JSON array for multi-word entrypoints
+		ep, _ := json.Marshal(s.Entrypoint)
+		run = append(run, "--entrypoint", string(ep))
+	}
+	run = append(run, s.Image)
+	run = append(run, s.Cmd...)
+
+	var b bytes.Buffer
+	writeUnitSection(&b, s, opts, true)
+
+	b.WriteString("[Service]\nEnvironment=PODMAN_SYSTEMD_UNIT=%n\n")
+	writeEnvironment(&b, vars, opts)
+	fmt.Fprintf(&b, "Restart=%s\n", restart(s.Restart))
+	b.WriteString("TimeoutStopSec=70\n")
+	b.WriteString("ExecStartPre=/bin/rm -f %t/%n.ctr-id\n")
+	fmt.Fprintf(&b, "ExecStart=%s run --cidfile=%%t/%%n.ctr-id --cgroups=no-conmon --rm --sdnotify=conmon --replace -d %s\n", podman, Exec(run))
+	fmt.Fprintf(&b, "ExecStop=%s stop --ignore --cidfile=%%t/%%n.ctr-id\n", podman)
+	fmt.Fprintf(&b, "ExecStopPost=%s rm -f --ignore --cidfile=%%t/%%n.ctr-id\n", podman)
+	b.WriteString("Type=notify\nNotifyAccess=all\n")
+	b.WriteString("\n[Install]\nWantedBy=default.target\n")
+	return b.Bytes()
+}
+
+func writeUnitSection(b *bytes.Buffer, s spec.Spec, opts Options, podman bool) {
+	desc := opts.Description
+	if desc == "" {
+		desc = s.Name
+	}
+	fmt.Fprintf(b, "# generated by dockerenv from container %s (%s)\n", s.Name, s.Image)
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(b, "Description=%s\n", strings.ReplaceAll(desc, "%", "%%"))
+	b.WriteString("Wants=network-online.target\nAfter=network-online.target\n")
+	if podman {
+		b.WriteString("RequiresMountsFor=%t/containers\n")
+	}
+	b.WriteString("\n")
+}
+
+func writeEnvironment(b *bytes.Buffer, vars []inspector.EnvVar, opts Options) {
+	if opts.EnvironmentFile != "" {
+		fmt.Fprintf(b, "EnvironmentFile=%s\n", opts.EnvironmentFile)
+		return
+	}
+	for _, ev := range vars {
+		b.WriteString(Environment(ev) + "\n")
+	}
+}
+
+// restart maps a docker restart policy to Restart=.
+func restart(policy string) string {
+	switch {
+	case policy == "always", policy == "unless-stopped":
+		return "always"
+	case strings.HasPrefix(policy, "on-failure"):
+		return "on-failure"
+	}
+	return "no"
+}
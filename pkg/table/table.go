@@ -0,0 +1,84 @@
+// Package table writes the fleet's variables as one row per variable, in
+// CSV (RFC 4180) or TSV.
+package table
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+// Columns is the header row. The order is part of the format: new columns
+// are only ever appended.
+var Columns = []string{"host", "container_id", "container_name", "image", "variable", "source", "secret", "value"}
+
+// Rows returns one row per variable of each container, in order. Every
+// value is shown according to policy, since the classifier misses secrets
+// such as passwords embedded in DSNs; the secret column says which
+// variables it flagged.
+func Rows(containers []inspector.Container, cls *secrets.Classifier, policy secrets.Policy) [][]string {
+	var rows [][]string
+	for _, ctr := range containers {
+		for _, ev := range ctr.Env {
+			secret := cls.IsSecret(ev.Name, ev.Value)
+			rows = append(rows, []string{
+				ctr.Host, ctr.ShortID(), ctr.Name, ctr.Image,
+				ev.Name, ev.Source, strconv.FormatBool(secret), policy.Apply(ev.Value),
+			})
+		}
+	}
+	return rows
+}
+
+// Defuse returns rows with a ' in front of every field a spreadsheet would
+// evaluate as a formula: those starting with =, +, -, @, tab or carriage
+// return.
+func Defuse(rows [][]string) [][]string {
+	defused := make([][]string, len(rows))
+	for i, row := range rows {
+		defused[i] = make([]string, len(row))
+		for j, f := range row {
+			if f != "" && strings.ContainsRune("=+-@\t\r", rune(f[0])) {
+				f = "'" + f
+			}
+			defused[i][j] = f
+		}
+	}
+	return defused
+}
+
+// WriteCSV writes the header and rows as RFC 4180 CSV, with CRLF line
+// endings and quoting where needed.
+func WriteCSV(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.Write(Columns); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// WriteTSV writes the header and rows as tab-separated values. TSV has no
+// quoting, so backslash, tab, newline and carriage return in fields are
+// written as \\, \t, \n and \r.
+func WriteTSV(w io.Writer, rows [][]string) error {
+	for _, row := range append([][]string{Columns}, rows...) {
+		fields := make([]string, len(row))
+		for i, f := range row {
+			fields[i] = tsvEscaper.Replace(f)
+		}
+		if _, err := io.WriteString(w, strings.Join(fields, "\t")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package table
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefuse(t *testing.T) {
+	rows := [][]string{{"=1+1", "+SUM(A1)", "-2", "@cmd", "\tx", "\rx", "plain", "", "a=b"}}
+	want := [][]string{{"'=1+1", "'+SUM(A1)", "'-2", "'@cmd", "'\tx", "'\rx", "plain", "", "a=b"}}
+	if got := Defuse(rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("Defuse(%q) = %q, want %q", rows, got, want)
+	}
+	if rows[0][0] != "=1+1" {
+		t.Errorf("Defuse modified its input: %q", rows[0][0])
+	}
+}
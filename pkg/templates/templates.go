@@ -0,0 +1,155 @@
+// Package templates renders containers through user supplied Go templates,
+// in the spirit of `docker inspect --format`.
+//
+// Each template is executed once per container with a *Data value:
+//
+//	.ID, .ShortID, .Name, .Image, .State, .Host   container metadata
+//	.Labels                                      map of label name to value
+//	.Env                                         ordered list of {.Name, .Value}
+//	.Certs                                       map of variable name to parsed
+//	                                             certificates (see certs.Info)
+//	.Key, .Value                                 the selected variable (get only)
+//
+// and the method .Get NAME, which returns the value of a variable or "".
+//
+// Besides the builtin template functions, these helpers are available:
+//
+//	json VALUE        encode VALUE as JSON
+//	upper, lower      change the case of a string
+//	b64enc, b64dec    standard base64 encoding
+//	mask              hide a value (see secrets.Mask)
+//	default DEF VAL   VAL, or DEF when VAL is empty
+//	join SEP LIST     join a list of strings
+//	trim              remove surrounding whitespace
+package templates
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/cmattoon/dockerenv/pkg/certs"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+// Data is the template data model for one container.
+type Data struct {
+	ID      string
+	ShortID string
+	Name    string
+	Image   string
+	State   string
+	Host    string
+	Labels  map[string]string
+	Env     []inspector.EnvVar
+	Certs   map[string][]certs.Info
+
+	// Key and Value are set when a single variable was selected.
+	Key   string
+	Value string
+}
+
+// NewData builds the data model for a container. Values that contain PEM
+// certificates are parsed into Certs.
+func NewData(ctr inspector.Container) *Data {
+	d := &Data{
+		ID:      ctr.ID,
+		ShortID: ctr.ShortID(),
+		Name:    ctr.Name,
+		Image:   ctr.Image,
+		State:   ctr.State,
+		Host:    ctr.Host,
+		Labels:  ctr.Labels,
+		Env:     ctr.Env,
+		Certs:   map[string][]certs.Info{},
+	}
+	for _, ev := range ctr.Env {
+		if !strings.Contains(ev.Value, "-----BEGIN CERTIFICATE") {
+			continue
+		}
+		pemData := strings.ReplaceAll(ev.Value, "\\n", "\n")
+		if infos, err := certs.ParsePEM([]byte(pemData)); err == nil && len(infos) > 0 {
+			d.Certs[ev.Name] = infos
+		}
+	}
+	return d
+}
+
+// Get returns the value of the named variable, or "" if it is not set.
+func (d *Data) Get(name string) string {
+	for _, ev := range d.Env {
+		if ev.Name == name {
+			return ev.Value
+		}
+	}
+	return ""
+}
+
+// Funcs are the helpers available to every template.
+var Funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"b64enc": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"b64dec": func(s string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		return string(b), err
+	},
+	"mask": secrets.Mask,
+	"default": func(def, v interface{}) interface{} {
+		if v == nil {
+			return def
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			if rv.Len() == 0 {
+				return def
+			}
+		}
+		return v
+	},
+	"join": func(sep string, list []string) string {
+		return strings.Join(list, sep)
+	},
+}
+
+// Template is a parsed --template.
+type Template struct {
+	t *template.Template
+}
+
+// Parse compiles text with the helper functions. Like docker, a trailing
+// newline is added when the template doesn't end with one.
+func Parse(text string) (*Template, error) {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	t, err := template.New("template").Funcs(Funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return &Template{t: t}, nil
+}
+
+// Execute renders the template for d.
+func (t *Template) Execute(w io.Writer, d *Data) error {
+	return t.t.Execute(w, d)
+}
+
+// IsTemplate reports whether s looks like a Go template rather than the
+// name of an output format.
+func IsTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
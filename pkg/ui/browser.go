@@ -0,0 +1,537 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cmattoon/dockerenv/pkg/certs"
+	"github.com/cmattoon/dockerenv/pkg/decode"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+type view int
+
+const (
+	viewList view = iota
+	viewDetail
+	viewCert
+)
+
+type pane int
+
+const (
+	paneContainers pane = iota
+	paneEnv
+)
+
+const helpText = "tab switch  / filter  enter open  r reveal  R reveal all  c cert  q quit"
+
+// Browser lists containers on the left and the ordered environment of the
+// selected container on the right.
+type Browser struct {
+	screen     Screen
+	inspectors []inspector.Inspector
+	filters    []string
+
+	containers []inspector.Container
+	owner      map[string]inspector.Inspector
+	loaded     map[string]inspector.Container
+
+	view    view
+	pane    pane
+	editing bool
+
+	ctrFilter, envFilter string
+	ctrIdx, envIdx       int
+	ctrOff, envOff       int
+	scroll               int
+
+	revealed  map[string]bool
+	revealAll bool
+	status    string
+
+	// now is replaceable so that expiry text is stable in tests.
+	now func() time.Time
+}
+
+// New returns a browser over the containers of every inspector that match
+// the given "key=value" filters.
+func New(screen Screen, inspectors []inspector.Inspector, filters ...string) *Browser {
+	return &Browser{
+		screen:     screen,
+		inspectors: inspectors,
+		filters:    filters,
+		owner:      map[string]inspector.Inspector{},
+		loaded:     map[string]inspector.Container{},
+		revealed:   map[string]bool{},
+		now:        time.Now,
+	}
+}
+
+// Run draws the browser and handles keys until the user quits or the
+// screen stops delivering keys.
+func (b *Browser) Run() error {
+	for _, ins := range b.inspectors {
+		list, err := ins.ListContainers(b.filters...)
+		if err != nil {
+			return err
+		}
+		for _, ctr := range list {
+			b.owner[ctr.ID] = ins
+			b.containers = append(b.containers, ctr)
+		}
+	}
+
+	for {
+		b.draw()
+		if err := b.screen.Show(); err != nil {
+			return err
+		}
+		key, err := b.screen.PollKey()
+		if err != nil {
+			return nil
+		}
+		if !b.handle(key) {
+			return nil
+		}
+	}
+}
+
+// visibleContainers applies the container filter.
+func (b *Browser) visibleContainers() []inspector.Container {
+	f := strings.ToLower(b.ctrFilter)
+	var out []inspector.Container
+	for _, ctr := range b.containers {
+		if f == "" || strings.Contains(strings.ToLower(ctr.Name+" "+ctr.Image+" "+ctr.ID), f) {
+			out = append(out, ctr)
+		}
+	}
+	return out
+}
+
+// current returns the fully inspected selected container.
+func (b *Browser) current() (inspector.Container, bool) {
+	list := b.visibleContainers()
+	if len(list) == 0 {
+		return inspector.Container{}, false
+	}
+	if b.ctrIdx >= len(list) {
+		b.ctrIdx = len(list) - 1
+	}
+	summary := list[b.ctrIdx]
+	if ctr, ok := b.loaded[summary.ID]; ok {
+		return ctr, true
+	}
+	ctr, err := b.owner[summary.ID].Inspect(summary.ID)
+	if err != nil {
+		b.status = err.Error()
+		return summary, true
+	}
+	b.loaded[summary.ID] = ctr
+	return ctr, true
+}
+
+// visibleEnv applies the variable filter to the selected container.
+func (b *Browser) visibleEnv() []inspector.EnvVar {
+	ctr, ok := b.current()
+	if !ok {
+		return nil
+	}
+	f := strings.ToLower(b.envFilter)
+	var out []inspector.EnvVar
+	for _, ev := range ctr.Env {
+		if f == "" || strings.Contains(strings.ToLower(ev.Name), f) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func (b *Browser) selectedVar() (inspector.EnvVar, bool) {
+	env := b.visibleEnv()
+	if len(env) == 0 {
+		return inspector.EnvVar{}, false
+	}
+	if b.envIdx >= len(env) {
+		b.envIdx = len(env) - 1
+	}
+	return env[b.envIdx], true
+}
+
+func (b *Browser) revealKey(ev inspector.EnvVar) string {
+	ctr, _ := b.current()
+	return ctr.ID + "/" + ev.Name
+}
+
+// display returns the value as it should be shown, masked unless revealed.
+func (b *Browser) display(ev inspector.EnvVar, value string) string {
+	if b.revealAll || b.revealed[b.revealKey(ev)] || !secrets.LooksSecret(ev.Name, ev.Value) {
+		return value
+	}
+	return secrets.Mask(value)
+}
+
+// handle processes one key and reports whether the browser keeps running.
+func (b *Browser) handle(k Key) bool {
+	b.status = ""
+	if k.Code == KeyCtrlC {
+		return false
+	}
+	if b.editing {
+		b.handleFilter(k)
+		return true
+	}
+	if b.view != viewList {
+		return b.handleDetail(k)
+	}
+
+	switch {
+	case k.Code == KeyRune && k.Rune == 'q':
+		return false
+	case k.Code == KeyTab, k.Code == KeyLeft, k.Code == KeyRight,
+		k.Code == KeyRune && (k.Rune == 'h' || k.Rune == 'l'):
+		if b.pane == paneContainers {
+			b.pane = paneEnv
+		} else {
+			b.pane = paneContainers
+		}
+	case k.Code == KeyUp, k.Code == KeyRune && k.Rune == 'k':
+		b.move(-1)
+	case k.Code == KeyDown, k.Code == KeyRune && k.Rune == 'j':
+		b.move(1)
+	case k.Code == KeyPgUp:
+		b.move(-b.pageSize())
+	case k.Code == KeyPgDn:
+		b.move(b.pageSize())
+	case k.Code == KeyHome, k.Code == KeyRune && k.Rune == 'g':
+		b.move(-1 << 20)
+	case k.Code == KeyEnd, k.Code == KeyRune && k.Rune == 'G':
+		b.move(1 << 20)
+	case k.Code == KeyRune && k.Rune == '/':
+		b.editing = true
+	case k.Code == KeyEsc:
+		if b.pane == paneEnv {
+			b.envFilter = ""
+		} else {
+			b.ctrFilter = ""
+		}
+	case k.Code == KeyRune && k.Rune == 'r':
+		if ev, ok := b.selectedVar(); ok && b.pane == paneEnv {
+			key := b.revealKey(ev)
+			b.revealed[key] = !b.revealed[key]
+		}
+	case k.Code == KeyRune && k.Rune == 'R':
+		b.revealAll = !b.revealAll
+	case k.Code == KeyEnter:
+		if b.pane == paneContainers {
+			b.pane = paneEnv
+			b.envIdx, b.envOff = 0, 0
+		} else if _, ok := b.selectedVar(); ok {
+			b.view, b.scroll = viewDetail, 0
+		}
+	case k.Code == KeyRune && k.Rune == 'c':
+		b.openCert()
+	}
+	return true
+}
+
+func (b *Browser) handleFilter(k Key) {
+	filter := &b.ctrFilter
+	if b.pane == paneEnv {
+		filter = &b.envFilter
+	}
+	switch k.Code {
+	case KeyEnter:
+		b.editing = false
+	case KeyEsc:
+		b.editing = false
+		*filter = ""
+	case KeyBackspace:
+		if r := []rune(*filter); len(r) > 0 {
+			*filter = string(r[:len(r)-1])
+		}
+	case KeyRune:
+		*filter += string(k.Rune)
+	}
+	if b.pane == paneEnv {
+		b.envIdx, b.envOff = 0, 0
+	} else {
+		b.ctrIdx, b.ctrOff = 0, 0
+		b.envIdx, b.envOff = 0, 0
+	}
+}
+
+func (b *Browser) handleDetail(k Key) bool {
+	switch {
+	case k.Code == KeyEsc, k.Code == KeyBackspace, k.Code == KeyRune && k.Rune == 'q':
+		if b.view == viewCert {
+			b.view = viewDetail
+		} else {
+			b.view = viewList
+		}
+		b.scroll = 0
+	case k.Code == KeyUp, k.Code == KeyRune && k.Rune == 'k':
+		if b.scroll > 0 {
+			b.scroll--
+		}
+	case k.Code == KeyDown, k.Code == KeyRune && k.Rune == 'j':
+		b.scroll++
+	case k.Code == KeyRune && k.Rune == 'r':
+		if ev, ok := b.selectedVar(); ok {
+			key := b.revealKey(ev)
+			b.revealed[key] = !b.revealed[key]
+		}
+	case k.Code == KeyRune && k.Rune == 'c':
+		b.openCert()
+	}
+	return true
+}
+
+func (b *Browser) openCert() {
+	ev, ok := b.selectedVar()
+	if !ok || b.pane != paneEnv {
+		return
+	}
+	if len(b.certificates(ev)) == 0 {
+		b.status = ev.Name + " does not contain a PEM certificate"
+		return
+	}
+	b.view, b.scroll = viewCert, 0
+}
+
+func (b *Browser) certificates(ev inspector.EnvVar) []certs.Info {
+	decoded, _ := decode.Auto(ev.Value)
+	infos, _ := certs.ParsePEM([]byte(decoded))
+	return infos
+}
+
+func (b *Browser) pageSize() int {
+	_, h := b.screen.Size()
+	if h > 4 {
+		return h - 3
+	}
+	return 1
+}
+
+func (b *Browser) move(delta int) {
+	idx, n := &b.ctrIdx, len(b.visibleContainers())
+	if b.pane == paneEnv {
+		idx, n = &b.envIdx, len(b.visibleEnv())
+	}
+	*idx += delta
+	if *idx >= n {
+		*idx = n - 1
+	}
+	if *idx < 0 {
+		*idx = 0
+	}
+	if b.pane == paneContainers {
+		b.envIdx, b.envOff = 0, 0
+	}
+}
+
+// keepVisible adjusts a scroll offset so that idx is within rows.
+func keepVisible(idx, off, rows int) int {
+	if idx < off {
+		return idx
+	}
+	if idx >= off+rows {
+		return idx - rows + 1
+	}
+	return off
+}
+
+func (b *Browser) draw() {
+	s := b.screen
+	s.Clear()
+	w, h := s.Size()
+	if h < 3 {
+		return
+	}
+
+	switch b.view {
+	case viewDetail:
+		b.drawDetail(w, h)
+	case viewCert:
+		b.drawCert(w, h)
+	default:
+		b.drawList(w, h)
+	}
+
+	switch {
+	case b.editing:
+		f := b.ctrFilter
+		if b.pane == paneEnv {
+			f = b.envFilter
+		}
+		s.Print(0, h-1, "/"+f+"_", StyleBold)
+	case b.status != "":
+		s.Print(0, h-1, b.status, StyleBold)
+	default:
+		s.Print(0, h-1, helpText, StyleDim)
+	}
+}
+
+func (b *Browser) drawList(w, h int) {
+	s := b.screen
+	list := b.visibleContainers()
+	title := fmt.Sprintf(" dockerenv  %d/%d containers", len(list), len(b.containers))
+	if b.ctrFilter != "" {
+		title += "  filter: " + b.ctrFilter
+	}
+	s.Print(0, 0, pad(title, w), StyleReverse)
+
+	rows := h - 2
+	lw := w / 3
+	if lw > 32 {
+		lw = 32
+	}
+
+	b.ctrOff = keepVisible(b.ctrIdx, b.ctrOff, rows)
+	for i := 0; i < rows && b.ctrOff+i < len(list); i++ {
+		ctr := list[b.ctrOff+i]
+		style := StyleNormal
+		if b.ctrOff+i == b.ctrIdx {
+			style = StyleBold
+			if b.pane == paneContainers {
+				style = StyleReverse
+			}
+		}
+		name := ctr.Name
+		if name == "" {
+			name = ctr.ShortID()
+		}
+		s.Print(0, i+1, pad(" "+name, lw-1), style)
+	}
+	for y := 1; y < h-1; y++ {
+		s.Print(lw-1, y, "│", StyleDim)
+	}
+
+	env := b.visibleEnv()
+	if b.envFilter != "" {
+		s.Print(lw+1, 0, "filter: "+b.envFilter, StyleReverse)
+	}
+	maxlen := 0
+	for _, ev := range env {
+		if len(ev.Name) > maxlen {
+			maxlen = len(ev.Name)
+		}
+	}
+	if limit := (w - lw) / 2; maxlen > limit {
+		maxlen = limit
+	}
+
+	b.envOff = keepVisible(b.envIdx, b.envOff, rows)
+	for i := 0; i < rows && b.envOff+i < len(env); i++ {
+		ev := env[b.envOff+i]
+		style := StyleNormal
+		if b.pane == paneEnv && b.envOff+i == b.envIdx {
+			style = StyleReverse
+		}
+		value := strings.ReplaceAll(b.display(ev, ev.Value), "\n", "\\n")
+		line := fmt.Sprintf(" %-*s  %s", maxlen, ev.Name, value)
+		s.Print(lw, i+1, pad(line, w-lw), style)
+	}
+}
+
+func (b *Browser) drawDetail(w, h int) {
+	ev, ok := b.selectedVar()
+	if !ok {
+		b.view = viewList
+		b.drawList(w, h)
+		return
+	}
+	ctr, _ := b.current()
+	b.screen.Print(0, 0, pad(fmt.Sprintf(" %s  (%s)", ev.Name, ctr.Name), w), StyleReverse)
+
+	var lines []string
+	lines = append(lines, "Raw value:")
+	lines = append(lines, wrap(b.display(ev, ev.Value), w-2)...)
+
+	decoded, chain := decode.Auto(ev.Value)
+	if len(chain) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Auto-decoded (%s):", chain))
+		lines = append(lines, wrap(b.display(ev, decoded), w-2)...)
+	}
+	if infos := b.certificates(ev); len(infos) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Contains %d certificate(s); press c for details", len(infos)))
+	}
+	b.drawLines(lines, h)
+}
+
+func (b *Browser) drawCert(w, h int) {
+	ev, _ := b.selectedVar()
+	b.screen.Print(0, 0, pad(" Certificates in "+ev.Name, w), StyleReverse)
+
+	now := b.now()
+	var lines []string
+	for _, info := range b.certificates(ev) {
+		expiry := "expires in " + info.NotAfter.Sub(now).Round(time.Hour).String()
+		if info.Expired(now) {
+			expiry = "EXPIRED"
+		}
+		lines = append(lines,
+			fmt.Sprintf("Certificate %d (CA: %v)", info.Index, info.IsCA),
+			"  Subject    : "+info.Subject,
+			"  Issuer     : "+info.Issuer,
+			"  Serial     : "+info.SerialNumber,
+			"  Not Before : "+info.NotBefore.Format(time.RFC3339),
+			"  Not After  : "+info.NotAfter.Format(time.RFC3339)+"  ("+expiry+")",
+		)
+		if len(info.DNSNames) > 0 {
+			lines = append(lines, "  DNS Names  : "+strings.Join(info.DNSNames, ", "))
+		}
+		if len(info.IPAddresses) > 0 {
+			lines = append(lines, "  IPs        : "+strings.Join(info.IPAddresses, ", "))
+		}
+		if len(info.KeyUsage) > 0 {
+			lines = append(lines, "  Key Usage  : "+strings.Join(info.KeyUsage, ", "))
+		}
+		if len(info.ExtKeyUsage) > 0 {
+			lines = append(lines, "  Ext Usage  : "+strings.Join(info.ExtKeyUsage, ", "))
+		}
+		lines = append(lines, "")
+	}
+	b.drawLines(lines, h)
+}
+
+func (b *Browser) drawLines(lines []string, h int) {
+	rows := h - 2
+	if last := len(lines) - rows; b.scroll > last {
+		b.scroll = last
+	}
+	if b.scroll < 0 {
+		b.scroll = 0
+	}
+	for i := 0; i < rows && b.scroll+i < len(lines); i++ {
+		b.screen.Print(1, i+1, lines[b.scroll+i], StyleNormal)
+	}
+}
+
+// pad extends s with spaces to width runes so that highlights span the row.
+func pad(s string, width int) string {
+	n := len([]rune(s))
+	if n >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// wrap splits a value into lines no wider than width runes.
+func wrap(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		r := []rune(line)
+		for len(r) > width {
+			out = append(out, string(r[:width]))
+			r = r[width:]
+		}
+		out = append(out, string(r))
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+// fakeInspector serves fixed containers. Methods the browser doesn't call
+// are left to the nil embedded interface.
+type fakeInspector struct {
+	inspector.Inspector
+	containers []inspector.Container
+}
+
+func (f *fakeInspector) ListContainers(filters ...string) ([]inspector.Container, error) {
+	var list []inspector.Container
+	for _, ctr := range f.containers {
+		ctr.Env = nil
+		list = append(list, ctr)
+	}
+	return list, nil
+}
+
+func (f *fakeInspector) Inspect(id string) (inspector.Container, error) {
+	for _, ctr := range f.containers {
+		if ctr.ID == id {
+			return ctr, nil
+		}
+	}
+	return inspector.Container{}, nil
+}
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testCert(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "api.example.com"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// run plays keys against a browser over two containers and returns the
+// final screen.
+func run(t *testing.T, tlsCrt string, keys ...Key) string {
+	t.Helper()
+	return browse(t, []inspector.EnvVar{
+		{Name: "LOG_LEVEL", Value: "debug"},
+		{Name: "DB_PASSWORD", Value: "correct-horse-battery"},
+		{Name: "TLS_CRT", Value: tlsCrt},
+	}, keys...)
+}
+
+// browse plays keys against a browser over api, which has env, and
+// worker, and returns the final screen.
+func browse(t *testing.T, env []inspector.EnvVar, keys ...Key) string {
+	t.Helper()
+	ins := &fakeInspector{containers: []inspector.Container{
+		{ID: "aaa111", Name: "api", Image: "shop/api", Env: env},
+		{ID: "bbb222", Name: "worker", Image: "shop/worker", Env: []inspector.EnvVar{
+			{Name: "QUEUE", Value: "jobs"},
+		}},
+	}}
+	screen := NewFakeScreen(100, 20, keys...)
+	b := New(screen, []inspector.Inspector{ins})
+	b.now = func() time.Time { return now }
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return screen.Contents()
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	got := run(t, "", Key{Code: KeyDown})
+	if !strings.Contains(got, "QUEUE") || strings.Contains(got, "LOG_LEVEL") {
+		t.Errorf("after moving down, want worker's variables shown:\n%s", got)
+	}
+
+	got = run(t, "", Key{Code: KeyDown}, Key{Code: KeyUp})
+	if !strings.Contains(got, "LOG_LEVEL") {
+		t.Errorf("after moving back up, want api's variables shown:\n%s", got)
+	}
+}
+
+func TestBrowserFilter(t *testing.T) {
+	keys := append([]Key{{Code: KeyRune, Rune: '/'}}, Runes("work")...)
+	keys = append(keys, Key{Code: KeyEnter})
+	got := run(t, "", keys...)
+	if !strings.Contains(got, "1/2 containers") || !strings.Contains(got, "QUEUE") {
+		t.Errorf("want only worker after filtering:\n%s", got)
+	}
+
+	keys = []Key{{Code: KeyTab}, {Code: KeyRune, Rune: '/'}}
+	keys = append(keys, Runes("pass")...)
+	keys = append(keys, Key{Code: KeyEnter})
+	got = run(t, "", keys...)
+	if !strings.Contains(got, "DB_PASSWORD") || strings.Contains(got, "LOG_LEVEL") {
+		t.Errorf("want only DB_PASSWORD after filtering variables:\n%s", got)
+	}
+}
+
+func TestBrowserReveal(t *testing.T) {
+	got := run(t, "")
+	if strings.Contains(got, "correct-horse-battery") || !strings.Contains(got, "********tery") {
+		t.Errorf("want DB_PASSWORD masked:\n%s", got)
+	}
+	if !strings.Contains(got, "debug") {
+		t.Errorf("want LOG_LEVEL shown:\n%s", got)
+	}
+
+	reveal := []Key{{Code: KeyTab}, {Code: KeyDown}, {Code: KeyRune, Rune: 'r'}}
+	got = run(t, "", reveal...)
+	if !strings.Contains(got, "correct-horse-battery") {
+		t.Errorf("want DB_PASSWORD revealed after r:\n%s", got)
+	}
+
+	got = run(t, "", append(reveal, Key{Code: KeyRune, Rune: 'r'})...)
+	if strings.Contains(got, "correct-horse-battery") {
+		t.Errorf("want DB_PASSWORD masked again after a second r:\n%s", got)
+	}
+
+	got = run(t, "", Key{Code: KeyRune, Rune: 'R'})
+	if !strings.Contains(got, "correct-horse-battery") {
+		t.Errorf("want every value revealed after R:\n%s", got)
+	}
+}
+
+func TestBrowserCertExpiry(t *testing.T) {
+	open := []Key{{Code: KeyTab}, {Code: KeyDown}, {Code: KeyDown}, {Code: KeyRune, Rune: 'c'}}
+
+	got := run(t, testCert(t, now.Add(48*time.Hour)), open...)
+	if !strings.Contains(got, "api.example.com") || !strings.Contains(got, "(expires in 48h0m0s)") {
+		t.Errorf("want the certificate expiring in 48h:\n%s", got)
+	}
+
+	got = run(t, testCert(t, now.Add(-time.Hour)), open...)
+	if !strings.Contains(got, "(EXPIRED)") {
+		t.Errorf("want the certificate expired:\n%s", got)
+	}
+}
+
+func TestBrowserMasksEncodedPrivateKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	body := strings.Split(pemKey, "\n")[1]
+	env := []inspector.EnvVar{{Name: "TLS_KEY_B64", Value: base64.StdEncoding.EncodeToString([]byte(pemKey))}}
+	open := []Key{{Code: KeyTab}, {Code: KeyEnter}}
+
+	got := browse(t, env, open...)
+	if !strings.Contains(got, "Auto-decoded (b64)") {
+		t.Fatalf("want the detail view of TLS_KEY_B64:\n%s", got)
+	}
+	if strings.Contains(got, "PRIVATE KEY") || strings.Contains(got, body[:16]) {
+		t.Errorf("the decoded private key is shown without r:\n%s", got)
+	}
+
+	got = browse(t, env, append(open, Key{Code: KeyRune, Rune: 'r'})...)
+	if !strings.Contains(got, "BEGIN EC PRIVATE KEY") {
+		t.Errorf("want the decoded private key shown after r:\n%s", got)
+	}
+}
@@ -0,0 +1,344 @@
+// Package ui is a small full-screen terminal browser for containers and
+// their environment.
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Style is how text is drawn.
+type Style int
+
+const (
+	StyleNormal Style = iota
+	StyleReverse
+	StyleBold
+	StyleDim
+)
+
+// KeyCode identifies a key. Printable keys use KeyRune.
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyPgUp
+	KeyPgDn
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyEsc
+	KeyTab
+	KeyBackspace
+	KeyCtrlC
+)
+
+// Key is a single key press.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Screen is what the browser draws on. TerminalScreen is the real thing;
+// FakeScreen lets tests drive the browser with scripted keys.
+type Screen interface {
+	// Size returns the number of columns and rows.
+	Size() (width, height int)
+	// Clear blanks the back buffer.
+	Clear()
+	// Print draws s at column x of row y. Text past the right edge is clipped.
+	Print(x, y int, s string, style Style)
+	// Show makes the back buffer visible.
+	Show() error
+	// PollKey blocks until a key is pressed. The browser exits on error.
+	PollKey() (Key, error)
+	// Close restores the terminal.
+	Close() error
+}
+
+type cell struct {
+	r     rune
+	style Style
+}
+
+// buffer is a grid of cells shared by both screen implementations.
+type buffer struct {
+	w, h  int
+	cells [][]cell
+}
+
+func (b *buffer) resize(w, h int) {
+	b.w, b.h = w, h
+	b.cells = make([][]cell, h)
+	for y := range b.cells {
+		b.cells[y] = make([]cell, w)
+	}
+	b.Clear()
+}
+
+func (b *buffer) Clear() {
+	for y := range b.cells {
+		for x := range b.cells[y] {
+			b.cells[y][x] = cell{r: ' '}
+		}
+	}
+}
+
+func (b *buffer) Print(x, y int, s string, style Style) {
+	if y < 0 || y >= b.h {
+		return
+	}
+	for _, r := range s {
+		if x >= b.w {
+			return
+		}
+		if r < 0x20 || r == 0x7f {
+			r = '?'
+		}
+		if x >= 0 {
+			b.cells[y][x] = cell{r: r, style: style}
+		}
+		x++
+	}
+}
+
+func (b *buffer) line(y int) string {
+	var sb strings.Builder
+	for _, c := range b.cells[y] {
+		sb.WriteRune(c.r)
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// TerminalScreen draws on a terminal using ANSI escape sequences.
+type TerminalScreen struct {
+	buffer
+	in    *os.File
+	out   io.Writer
+	state *term.State
+	keys  []Key
+}
+
+// NewTerminalScreen switches the terminal to raw mode and the alternate
+// screen. Close must be called to restore it.
+func NewTerminalScreen() (*TerminalScreen, error) {
+	in := os.Stdin
+	if !term.IsTerminal(int(in.Fd())) {
+		return nil, fmt.Errorf("stdin is not a terminal")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	s := &TerminalScreen{in: in, out: os.Stdout, state: state}
+	s.resize(s.Size())
+	fmt.Fprint(s.out, "\x1b[?1049h\x1b[?25l")
+	return s, nil
+}
+
+// Size implements Screen.
+func (s *TerminalScreen) Size() (int, int) {
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// Clear implements Screen, picking up any change in terminal size.
+func (s *TerminalScreen) Clear() {
+	if w, h := s.Size(); w != s.w || h != s.h {
+		s.resize(w, h)
+	}
+	s.buffer.Clear()
+}
+
+// Show implements Screen.
+func (s *TerminalScreen) Show() error {
+	var out bytes.Buffer
+	out.WriteString("\x1b[H")
+	for y := 0; y < s.h; y++ {
+		fmt.Fprintf(&out, "\x1b[%d;1H", y+1)
+		style := StyleNormal
+		for _, c := range s.cells[y] {
+			if c.style != style {
+				out.WriteString(sgr[c.style])
+				style = c.style
+			}
+			out.WriteRune(c.r)
+		}
+		out.WriteString(sgr[StyleNormal])
+	}
+	_, err := s.out.Write(out.Bytes())
+	return err
+}
+
+var sgr = map[Style]string{
+	StyleNormal:  "\x1b[0m",
+	StyleReverse: "\x1b[0;7m",
+	StyleBold:    "\x1b[0;1m",
+	StyleDim:     "\x1b[0;2m",
+}
+
+// PollKey implements Screen.
+func (s *TerminalScreen) PollKey() (Key, error) {
+	for len(s.keys) == 0 {
+		buf := make([]byte, 64)
+		n, err := s.in.Read(buf)
+		if err != nil {
+			return Key{}, err
+		}
+		s.keys = parseKeys(buf[:n])
+	}
+	k := s.keys[0]
+	s.keys = s.keys[1:]
+	return k, nil
+}
+
+// Close implements Screen.
+func (s *TerminalScreen) Close() error {
+	fmt.Fprint(s.out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+	return term.Restore(int(s.in.Fd()), s.state)
+}
+
+var escapes = map[string]KeyCode{
+	"\x1b[A": KeyUp, "\x1b[B": KeyDown, "\x1b[C": KeyRight, "\x1b[D": KeyLeft,
+	"\x1bOA": KeyUp, "\x1bOB": KeyDown, "\x1bOC": KeyRight, "\x1bOD": KeyLeft,
+	"\x1b[5~": KeyPgUp, "\x1b[6~": KeyPgDn,
+	"\x1b[H": KeyHome, "\x1b[F": KeyEnd, "\x1b[1~": KeyHome, "\x1b[4~": KeyEnd,
+}
+
+// parseKeys splits the bytes of one terminal read into key presses. A lone
+// ESC is the escape key; unknown escape sequences are dropped.
+func parseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			if len(b) == 1 {
+				keys = append(keys, Key{Code: KeyEsc})
+				break
+			}
+			matched := false
+			for seq, code := range escapes {
+				if bytes.HasPrefix(b, []byte(seq)) {
+					keys = append(keys, Key{Code: code})
+					b = b[len(seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				if b[1] != '[' && b[1] != 'O' {
+					keys = append(keys, Key{Code: KeyEsc})
+					b = b[1:]
+					continue
+				}
+				// skip to the final byte of the unknown sequence
+				i := 2
+				for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+					i++
+				}
+				b = b[min(i+1, len(b)):]
+			}
+			continue
+		}
+
+		switch b[0] {
+		case '\r', '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+		case '\t':
+			keys = append(keys, Key{Code: KeyTab})
+		case 0x7f, 0x08:
+			keys = append(keys, Key{Code: KeyBackspace})
+		case 0x03:
+			keys = append(keys, Key{Code: KeyCtrlC})
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r >= 0x20 {
+				keys = append(keys, Key{Code: KeyRune, Rune: r})
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// FakeScreen is an in-memory Screen for tests. Keys are played back in
+// order; PollKey returns io.EOF once they run out.
+type FakeScreen struct {
+	buffer
+	keys  []Key
+	shown int
+}
+
+// NewFakeScreen returns a w x h screen that will replay keys.
+func NewFakeScreen(w, h int, keys ...Key) *FakeScreen {
+	s := &FakeScreen{keys: keys}
+	s.resize(w, h)
+	return s
+}
+
+// Runes converts text to KeyRune presses, for typing filters.
+func Runes(text string) []Key {
+	var keys []Key
+	for _, r := range text {
+		keys = append(keys, Key{Code: KeyRune, Rune: r})
+	}
+	return keys
+}
+
+// Size implements Screen.
+func (s *FakeScreen) Size() (int, int) { return s.w, s.h }
+
+// Show implements Screen.
+func (s *FakeScreen) Show() error {
+	s.shown++
+	return nil
+}
+
+// PollKey implements Screen.
+func (s *FakeScreen) PollKey() (Key, error) {
+	if len(s.keys) == 0 {
+		return Key{}, io.EOF
+	}
+	k := s.keys[0]
+	s.keys = s.keys[1:]
+	return k, nil
+}
+
+// Close implements Screen.
+func (s *FakeScreen) Close() error { return nil }
+
+// Line returns row y with trailing spaces removed.
+func (s *FakeScreen) Line(y int) string { return s.line(y) }
+
+// Contents returns every row, joined by newlines.
+func (s *FakeScreen) Contents() string {
+	lines := make([]string, s.h)
+	for y := range lines {
+		lines[y] = s.line(y)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// StyleAt returns the style of the cell at x, y.
+func (s *FakeScreen) StyleAt(x, y int) Style { return s.cells[y][x].style }
@@ -0,0 +1,213 @@
+// Package varfile turns a container's environment into typed, renamed
+// variables and writes them as Terraform .tfvars and variable
+// declarations, or as Helm values.
+//
+// Output is sorted by key so it can be diffed between runs.
+package varfile
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cmattoon/dockerenv/pkg/hcl"
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+	"github.com/cmattoon/dockerenv/pkg/secrets"
+)
+
+// Type is the inferred type of a value.
+type Type string
+
+const (
+	String Type = "string"
+	Number Type = "number"
+	Bool   Type = "bool"
+)
+
+var integer = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+
+// helmMaxInt bounds the integers written to values.yaml as numbers. Helm
+// reads numbers as float64 and templates print 1000000 and above as
+// 1e+06, so larger ones are written as strings.
+const helmMaxInt = 1000000
+
+// Infer returns the type of value. Only the exact spellings "true",
+// "false" and integers without leading zeros that fit in 64 bits are
+// treated as anything but a string, so values such as "007", "1e3" or
+// "1.10" keep their text.
+func Infer(value string) Type {
+	switch {
+	case value == "true" || value == "false":
+		return Bool
+	case integer.MatchString(value):
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return Number
+		}
+	}
+	return String
+}
+
+// Convention converts variable names to keys.
+type Convention string
+
+const (
+	Keep  Convention = "keep"
+	Snake Convention = "snake"
+	Camel Convention = "camel"
+	Upper Convention = "upper"
+)
+
+// ParseConvention returns the naming convention with the given name.
+func ParseConvention(name string) (Convention, error) {
+	switch c := Convention(name); c {
+	case Keep, Snake, Camel, Upper:
+		return c, nil
+	}
+	return Keep, fmt.Errorf("unknown key convention '%s' (valid: keep, snake, camel, upper)", name)
+}
+
+var wordSep = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// Key converts name, e.g. MYAPP_DB_HOST becomes myapp_db_host (snake) or
+// myappDbHost (camel).
+func (c Convention) Key(name string) string {
+	words := wordSep.Split(name, -1)
+	var parts []string
+	for _, w := range words {
+		if w != "" {
+			parts = append(parts, w)
+		}
+	}
+	switch c {
+	case Snake:
+		return strings.ToLower(strings.Join(parts, "_"))
+	case Upper:
+		return strings.ToUpper(strings.Join(parts, "_"))
+	case Camel:
+		for i, p := range parts {
+			p = strings.ToLower(p)
+			if i > 0 {
+				p = strings.ToUpper(p[:1]) + p[1:]
+			}
+			parts[i] = p
+		}
+		return strings.Join(parts, "")
+	}
+	return name
+}
+
+// Entry is one converted variable.
+type Entry struct {
+	Key       string
+	Name      string
+	Value     string
+	Type      Type
+	Sensitive bool
+}
+
+// Build converts vars with conv and marks those cls classifies as secret.
+// Entries are sorted by key. Names that convert to an empty or already
+// used key are returned in skipped.
+func Build(vars []inspector.EnvVar, cls *secrets.Classifier, conv Convention) (entries []Entry, skipped []string) {
+	seen := map[string]bool{}
+	for _, ev := range vars {
+		key := conv.Key(ev.Name)
+		if key == "" || seen[key] {
+			skipped = append(skipped, ev.Name)
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, Entry{
+			Key:       key,
+			Name:      ev.Name,
+			Value:     ev.Value,
+			Type:      Infer(ev.Value),
+			Sensitive: cls != nil && cls.IsSecret(ev.Name, ev.Value),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, skipped
+}
+
+// Split separates sensitive entries from the rest, keeping their order.
+func Split(entries []Entry) (plain, sensitive []Entry) {
+	for _, e := range entries {
+		if e.Sensitive {
+			sensitive = append(sensitive, e)
+		} else {
+			plain = append(plain, e)
+		}
+	}
+	return plain, sensitive
+}
+
+// TFVars writes entries as a .tfvars file, with the equals signs aligned
+// as `terraform fmt` does.
+func TFVars(entries []Entry) ([]byte, error) {
+	attrs := make([]hcl.Attr, 0, len(entries))
+	for _, e := range entries {
+		if !hcl.IsIdent(e.Key) {
+			return nil, fmt.Errorf("%s: '%s' is not a valid terraform variable name", e.Name, e.Key)
+		}
+		value := e.Value
+		if e.Type == String {
+			value = hcl.Quote(e.Value)
+		}
+		attrs = append(attrs, hcl.Attr{Name: e.Key, Value: value})
+	}
+	var b bytes.Buffer
+	err := hcl.WriteAttrs(&b, "", attrs)
+	return b.Bytes(), err
+}
+
+// TFVariables writes a variable block declaring each entry's type.
+// Sensitive entries are marked `sensitive = true`.
+func TFVariables(entries []Entry) ([]byte, error) {
+	var b bytes.Buffer
+	for i, e := range entries {
+		if !hcl.IsIdent(e.Key) {
+			return nil, fmt.Errorf("%s: '%s' is not a valid terraform variable name", e.Name, e.Key)
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "variable %s {\n", hcl.Quote(e.Key))
+		if e.Sensitive {
+			fmt.Fprintf(&b, "  type      = %s\n  sensitive = true\n", e.Type)
+		} else {
+			fmt.Fprintf(&b, "  type = %s\n", e.Type)
+		}
+		b.WriteString("}\n")
+	}
+	return b.Bytes(), nil
+}
+
+// HelmValues writes entries as a values.yaml map under root (at the top
+// level if root is empty), with typed values.
+func HelmValues(entries []Entry, root string) ([]byte, error) {
+	values := yaml.MapSlice{}
+	for _, e := range entries {
+		values = append(values, yaml.MapItem{Key: e.Key, Value: typed(e)})
+	}
+	if root == "" {
+		return yaml.Marshal(values)
+	}
+	return yaml.Marshal(yaml.MapSlice{{Key: root, Value: values}})
+}
+
+func typed(e Entry) interface{} {
+	switch e.Type {
+	case Bool:
+		return e.Value == "true"
+	case Number:
+		if i, err := strconv.ParseInt(e.Value, 10, 64); err == nil && i > -helmMaxInt && i < helmMaxInt {
+			return i
+		}
+	}
+	return e.Value
+}
@@ -0,0 +1,60 @@
+package varfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmattoon/dockerenv/pkg/inspector"
+)
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Type
+	}{
+		{"true", Bool},
+		{"false", Bool},
+		{"True", String},
+		{"0", Number},
+		{"42", Number},
+		{"-7", Number},
+		{"1000000", Number},
+		{"007", String},
+		{"1e3", String},
+		{"1.10", String},
+		{"3.14", String},
+		{"99999999999999999999", String},
+		{"", String},
+		{"v1", String},
+	}
+	for _, tt := range tests {
+		if got := Infer(tt.value); got != tt.want {
+			t.Errorf("Infer(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHelmValues(t *testing.T) {
+	vars := []inspector.EnvVar{
+		{Name: "WORKERS", Value: "4"},
+		{Name: "MAX_BYTES", Value: "1000000"},
+		{Name: "VERSION", Value: "1.10"},
+		{Name: "DEBUG", Value: "false"},
+	}
+	entries, _ := Build(vars, nil, Snake)
+	data, err := HelmValues(entries, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `debug: false
+max_bytes: "1000000"
+version: "1.10"
+workers: 4
+`
+	if got := string(data); got != want {
+		t.Errorf("HelmValues =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(string(data), "e+") {
+		t.Errorf("HelmValues wrote a float:\n%s", data)
+	}
+}